@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const mapSearchURL = "https://www.openstreetmap.org/search?query="
+
+// Matches German addresses such as "Königstraße 1, 70173 Stuttgart". The
+// street may contain commas itself, e.g. if preceded by a building name.
+var addressRegexp = regexp.MustCompile(`^(.+?),\s*(\d{5})\s+(.+)$`)
+
+// parseAddress splits address into its street, postal code and city. All
+// of them are empty if address doesn't look like "Street Nr, PLZ City".
+func parseAddress(address string) (string, string, string) {
+	m := addressRegexp.FindStringSubmatch(strings.TrimSpace(address))
+	if m == nil {
+		return "", "", ""
+	}
+	return strings.TrimSpace(m[1]), m[2], strings.TrimSpace(m[3])
+}
+
+// setAddressParts sets the structured address fields of itm from its
+// address.
+func setAddressParts(itm *item) {
+	itm.Street, itm.PostalCode, itm.City = parseAddress(itm.Address)
+	itm.MapURL = mapURL(itm.Address)
+}
+
+// mapURL returns the URL searching address on a map, an empty string if
+// address is blank.
+func mapURL(address string) string {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return ""
+	}
+	return mapSearchURL + url.QueryEscape(address)
+}
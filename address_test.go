@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		address                  string
+		street, postalCode, city string
+	}{
+		{"Königstraße 1, 70173 Stuttgart", "Königstraße 1", "70173", "Stuttgart"},
+		{"Müller-Thurgaustraße 12, 79346 Endingen", "Müller-Thurgaustraße 12", "79346", "Endingen"},
+		{"Müller-Thurgaustraße 12a, 79346 Endingen am Kaiserstuhl", "Müller-Thurgaustraße 12a", "79346", "Endingen am Kaiserstuhl"},
+		{"  Hauptstraße 3 ,  72072   Tübingen  ", "Hauptstraße 3", "72072", "Tübingen"},
+		// The street may contain commas itself
+		{"Markthalle, Stand 5, Dorotheenstraße 4, 70173 Stuttgart", "Markthalle, Stand 5, Dorotheenstraße 4", "70173", "Stuttgart"},
+		{"Bahnhofstraße 1, 01067 Dresden-Neustadt", "Bahnhofstraße 1", "01067", "Dresden-Neustadt"},
+		// Not "Street Nr, PLZ City"
+		{"", "", "", ""},
+		{"Müller-Thurgaustraße 12 79346 Endingen", "", "", ""},
+		{"Müller-Thurgaustraße 12, Endingen", "", "", ""},
+		{"Müller-Thurgaustraße 12, 7934 Endingen", "", "", ""},
+		{"Müller-Thurgaustraße 12, 79346", "", "", ""},
+	} {
+		street, postalCode, city := parseAddress(tc.address)
+		if street != tc.street || postalCode != tc.postalCode || city != tc.city {
+			t.Errorf(
+				"parseAddress(%q) = %q, %q, %q, want %q, %q, %q",
+				tc.address, street, postalCode, city, tc.street, tc.postalCode, tc.city,
+			)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Hex characters of the tokens replacing names and addresses
+const anonymizeTokenLen = 12
+
+// anonymizer masks the fields of items identifying businesses, which
+// may be individuals, see -anonymize.
+type anonymizer struct {
+	// Keys the tokens, LMK_ANONYMIZE_SECRET, so they can't be reversed
+	// by hashing guessed names, unkeyed if empty
+	secret string
+}
+
+// anonymize returns copies of the items with the name and address
+// replaced by tokens, see token. The street, map URL and coordinates
+// are cleared, the postal code and city are kept.
+func (a anonymizer) anonymize(items []*item) []*item {
+	anonymized := make([]*item, 0, len(items))
+	for _, itm := range items {
+		c := *itm
+		c.Name = "Betrieb " + a.token("name", itm.Name)
+		c.Address = "Anschrift " + a.token("address", itm.Address)
+		c.Street = ""
+		c.MapURL = ""
+		c.Latitude, c.Longitude = 0, 0
+		anonymized = append(anonymized, &c)
+	}
+	return anonymized
+}
+
+// token returns the token of the field's value, the leading hex
+// characters of the HMAC-SHA256, keyed by the secret, of the field's name
+// and its value ignoring case and whitespace. The same value always maps
+// to the same token.
+func (a anonymizer) token(field, value string) string {
+	mac := hmac.New(sha256.New, []byte(a.secret))
+	mac.Write([]byte(field + ":" + strings.ToLower(strings.Join(strings.Fields(value), " "))))
+	return hex.EncodeToString(mac.Sum(nil))[:anonymizeTokenLen]
+}
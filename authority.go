@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Key of the config file setting the authority normalization table
+const configAuthoritiesKey = "authorities"
+
+// authorityNormalizer canonicalizes the differently formatted names of
+// the same authority, e.g. "LRA X" to "Landratsamt X". Its keys are the
+// names as scraped, normalized by normalizeText.
+type authorityNormalizer map[string]string
+
+// newAuthorityNormalizer returns the normalizer of the table mapping
+// scraped authority names to their canonical ones.
+func newAuthorityNormalizer(table map[string]string) authorityNormalizer {
+	n := make(authorityNormalizer, len(table))
+	for raw, canonical := range table {
+		n[normalizeText(raw)] = canonical
+	}
+	return n
+}
+
+// normalize returns the canonical name of authority, authority itself if
+// it has none.
+func (n authorityNormalizer) normalize(authority string) string {
+	if canonical, ok := n[normalizeText(authority)]; ok {
+		return canonical
+	}
+	return authority
+}
+
+// configAuthorities returns the authority normalization table of the
+// config file setting v, a mapping of scraped names to canonical ones.
+func configAuthorities(v any) (map[string]string, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected a mapping of scraped to canonical authority names")
+	}
+
+	table := make(map[string]string, len(m))
+	for raw, canonical := range m {
+		s, ok := canonical.(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("invalid canonical name of authority %q", raw)
+		}
+		table[raw] = s
+	}
+	return table, nil
+}
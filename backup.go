@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// Copies the database consistently even while it's being written,
+	// e.g. by another instance watching, unlike copying the file, which
+	// may miss the pages still in the WAL. The target must be empty.
+	vacuumIntoStmt = `
+		vacuum into $1;
+	`
+
+	// Of validating a backup before restoring it
+	integrityCheckStmt = `
+		pragma integrity_check;
+	`
+	selectItemsColumnsStmt = `
+		select name from pragma_table_info('items');
+	`
+	// Databases replayed from an SQL dump or created before migrations
+	// were tracked have none
+	hasSchemaVersionStmt = `
+		select count(*) from sqlite_master where type = 'table' and name = 'schema_version';
+	`
+	// Of holding the write lock while restoring, see lockSQLite
+	exclusiveLockingModeStmt = `
+		pragma locking_mode = exclusive;
+	`
+	beginImmediateStmt = `
+		begin immediate;
+	`
+	commitStmt = `
+		commit;
+	`
+	// Moves the WAL into the database, which is about to be replaced
+	walCheckpointStmt = `
+		pragma wal_checkpoint(truncate);
+	`
+)
+
+// restoreRequiredColumns returns the columns of the items table every
+// schema version has, the others are added by migrating a restored
+// backup of an older version.
+func restoreRequiredColumns() []string {
+	return []string{
+		"id",
+		"hash",
+		"authority",
+		"published_at",
+		"found_at",
+		"name",
+		"address",
+		"reason",
+		"legal_basis",
+		"info",
+	}
+}
+
+// backupSQLite writes a consistent copy of the sqlite database to dest,
+// replacing it atomically. It returns the size of the copy in bytes.
+func backupSQLite(ctx context.Context, db *sql.DB, dest string, perm os.FileMode) (int64, error) {
+	var size int64
+	if err := createFileAtomic(dest, perm, func(f *os.File) error {
+		if _, err := db.ExecContext(ctx, vacuumIntoStmt, f.Name()); err != nil {
+			return fmt.Errorf("failed to back up database: %w", err)
+		}
+		fi, err := os.Stat(f.Name())
+		if err != nil {
+			return fmt.Errorf("failed to stat backup: %w", err)
+		}
+		size = fi.Size()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// runBackup writes a consistent copy of the sqlite database to dest. The
+// copy has the permissions of the database.
+func runBackup(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	dest string,
+) error {
+	if dest == "" {
+		return errors.New("no backup file given")
+	}
+	if storageCfg.databaseURL != "" {
+		return errors.New("only sqlite databases can be backed up, use pg_dump for Postgres")
+	}
+	if storageCfg.sqlite.inMemory() {
+		return errors.New("an in-memory database can't be backed up, export it instead")
+	}
+
+	fi, err := os.Stat(storageCfg.sqlite.file)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	st, err := openSQLite(ctx, l, storageCfg.sqlite)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	size, err := backupSQLite(ctx, st.db, dest, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	l.InfoContext(
+		ctx,
+		"successfully backed up database",
+		"file", dest,
+		"bytes", size,
+	)
+
+	return nil
+}
+
+// runRestore replaces the sqlite database with the backup src, see
+// runBackup, once it's validated. The database must not be in use,
+// e.g. by another instance watching.
+func runRestore(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	src string,
+) error {
+	if src == "" {
+		return errors.New("no backup file to restore given")
+	}
+	if storageCfg.databaseURL != "" {
+		return errors.New("only sqlite databases can be restored, use pg_restore for Postgres")
+	}
+	if storageCfg.sqlite.inMemory() {
+		return errors.New("a backup can't be restored into an in-memory database, import it instead")
+	}
+
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	// Only read, vacuuming into another file doesn't change it
+	db, err := sql.Open("sqlite", sqliteDSN(sqliteConfig{file: src}))
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close backup: %w", err).Error())
+		}
+	}()
+	if err := validateBackup(ctx, l, db); err != nil {
+		return fmt.Errorf("invalid backup %q: %w", src, err)
+	}
+
+	// Copied rather than renamed so the backup is kept, and consistently
+	// in case it's in WAL mode itself
+	replace := func(perm os.FileMode) error {
+		_, err := backupSQLite(ctx, db, storageCfg.sqlite.file, perm)
+		return err
+	}
+	if fi, err := os.Stat(storageCfg.sqlite.file); err == nil {
+		if err := lockSQLite(ctx, l, storageCfg.sqlite, func() error { return replace(fi.Mode().Perm()) }); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat sqlite database: %w", err)
+	} else if err := replace(srcInfo.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Migrates the backup if it's of an older version
+	st, err := openSQLite(ctx, l, storageCfg.sqlite)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	var items, history int
+	for _, c := range []struct {
+		n    *int
+		stmt string
+	}{
+		{&items, countItemsStmt},
+		{&history, countHistoryStmt},
+	} {
+		if err := st.db.QueryRowContext(ctx, c.stmt).Scan(c.n); err != nil {
+			return fmt.Errorf("failed to count restored rows: %w", err)
+		}
+	}
+
+	l.InfoContext(
+		ctx,
+		"successfully restored database",
+		"file", src,
+		"items", items,
+		"history", history,
+	)
+
+	return nil
+}
+
+// validateBackup checks the integrity of the backup and that it has the
+// items table.
+func validateBackup(ctx context.Context, l *slog.Logger, db *sql.DB) error {
+	problems, err := queryStrings(ctx, l, db, integrityCheckStmt)
+	if err != nil {
+		return fmt.Errorf("failed to check integrity: %w", err)
+	}
+	if len(problems) != 1 || problems[0] != "ok" {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+
+	columns, err := queryStrings(ctx, l, db, selectItemsColumnsStmt)
+	if err != nil {
+		return fmt.Errorf("failed to get columns of items: %w", err)
+	}
+	if len(columns) == 0 {
+		return errors.New("no items table")
+	}
+	for _, c := range restoreRequiredColumns() {
+		if !slices.Contains(columns, c) {
+			return fmt.Errorf("items table has no column %q", c)
+		}
+	}
+
+	// Restoring a newer version's backup would fail to open afterwards.
+	// Without a schema version all migrations apply, those adding what
+	// exists already are no-ops.
+	var hasVersion, version int
+	if err := db.QueryRowContext(ctx, hasSchemaVersionStmt).Scan(&hasVersion); err != nil {
+		return fmt.Errorf("failed to look up schema version: %w", err)
+	}
+	if hasVersion > 0 {
+		if err := db.QueryRowContext(ctx, selectSchemaVersionStmt).Scan(&version); err != nil {
+			return fmt.Errorf("failed to get schema version: %w", err)
+		}
+	}
+	if supported := len(sqliteMigrations()); version > supported {
+		return fmt.Errorf("schema version %d is newer than the supported version %d", version, supported)
+	}
+
+	return nil
+}
+
+// lockSQLite calls replace, which replaces the database, while holding
+// an exclusive lock of it, so nobody accesses the database meanwhile,
+// and after moving its WAL into it, so no WAL is left behind to apply to
+// the database replacing it. It fails right away if the database is in
+// use, e.g. opened by another instance, which holds on to the replaced
+// database otherwise.
+func lockSQLite(ctx context.Context, l *slog.Logger, cfg sqliteConfig, replace func() error) error {
+	cfg.busyTimeout = 0
+	db, err := sql.Open("sqlite", sqliteDSN(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close sqlite database: %w", err).Error())
+		}
+	}()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to sqlite database: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close sqlite connection: %w", err).Error())
+		}
+	}()
+
+	// Keeps the locks once acquired until the connection is closed, other
+	// connections can't even read
+	if _, err := conn.ExecContext(ctx, exclusiveLockingModeStmt); err != nil {
+		return fmt.Errorf("failed to lock sqlite database: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, beginImmediateStmt); err != nil {
+		return fmt.Errorf("refusing to restore, the database is in use, e.g. by another instance: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, commitStmt); err != nil {
+		return fmt.Errorf("failed to lock sqlite database: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, walCheckpointStmt); err != nil {
+		return fmt.Errorf("failed to checkpoint sqlite database: %w", err)
+	}
+
+	return replace()
+}
+
+// queryStrings returns the first column of the rows selected by query.
+func queryStrings(ctx context.Context, l *slog.Logger, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapped by the callers
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close rows: %w", err).Error())
+		}
+	}()
+
+	var ss []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err //nolint:wrapcheck // Wrapped by the callers
+		}
+		ss = append(ss, s)
+	}
+
+	return ss, rows.Err() //nolint:wrapcheck // Wrapped by the callers
+}
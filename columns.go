@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	columnAuthority      = "authority"
+	columnPublishedAt    = "published_at"
+	columnFoundAt        = "found_at"
+	columnName           = "name"
+	columnAddress        = "address"
+	columnReason         = "reason"
+	columnLegalBasis     = "legal_basis"
+	columnInfo           = "info"
+	columnPublishedAtEnd = "published_at_end"
+	columnFoundAtEnd     = "found_at_end"
+	columnStreet         = "street"
+	columnPostalCode     = "postal_code"
+	columnCity           = "city"
+	columnState          = "state"
+	columnLatitude       = "latitude"
+	columnLongitude      = "longitude"
+	columnMapURL         = "map_url"
+	columnFirstSeen      = "first_seen"
+	columnLastSeen       = "last_seen"
+	columnVanished       = "vanished"
+
+	columnAuthorityNormalized = "authority_normalized"
+	columnDeletedAt           = "deleted_at"
+
+	// Number of leading columns shown when details are hidden
+	numSummaryColumns = 5
+	// Number of leading columns output unless others are selected, the
+	// scraped ones. The columns added later are only output if selected
+	// with -columns, so the output of existing setups doesn't change.
+	numDefaultColumns = 8
+)
+
+type column struct {
+	name  string // Used to select the column, matches the item's JSON key
+	label string
+
+	// text returns the column's value as scraped
+	text func(itm *item) string
+	// value returns the column's typed value, e.g. a *time.Time for
+	// dates, nil if unknown
+	value func(itm *item) any
+}
+
+func stringColumn(name, label string, field func(itm *item) string) column {
+	return column{
+		name:  name,
+		label: label,
+		text:  field,
+		value: func(itm *item) any { return field(itm) },
+	}
+}
+
+// itemColumns returns all columns in their natural order, see
+// defaultColumns for the ones output by default.
+func itemColumns() []column {
+	return []column{
+		stringColumn(columnAuthority, labelAuthority, func(itm *item) string { return itm.Authority }),
+		{
+			name:  columnPublishedAt,
+			label: labelPublishedAt,
+			text:  func(itm *item) string { return itm.PublishedAtStr },
+			value: func(itm *item) any { return optionalTime(itm.PublishedAt) },
+		},
+		{
+			name:  columnFoundAt,
+			label: labelFoundAt,
+			text:  func(itm *item) string { return itm.FoundAtStr },
+			value: func(itm *item) any { return optionalTime(itm.FoundAt) },
+		},
+		stringColumn(columnName, labelName, func(itm *item) string { return itm.Name }),
+		stringColumn(columnAddress, labelAddress, func(itm *item) string { return itm.Address }),
+		stringColumn(columnReason, labelReason, func(itm *item) string { return itm.Reason }),
+		stringColumn(columnLegalBasis, labelLegalBasis, func(itm *item) string { return itm.LegalBasis }),
+		stringColumn(columnInfo, labelInfo, func(itm *item) string { return itm.Info }),
+		{
+			name:  columnPublishedAtEnd,
+			label: labelPublishedAtEnd,
+			text:  func(itm *item) string { return formatDate(itm.PublishedAtEnd) },
+			value: func(itm *item) any { return optionalTime(itm.PublishedAtEnd) },
+		},
+		{
+			name:  columnFoundAtEnd,
+			label: labelFoundAtEnd,
+			text:  func(itm *item) string { return formatDate(itm.FoundAtEnd) },
+			value: func(itm *item) any { return optionalTime(itm.FoundAtEnd) },
+		},
+		stringColumn(columnStreet, labelStreet, func(itm *item) string { return itm.Street }),
+		stringColumn(columnPostalCode, labelPostalCode, func(itm *item) string { return itm.PostalCode }),
+		stringColumn(columnCity, labelCity, func(itm *item) string { return itm.City }),
+		stringColumn(columnState, labelState, func(itm *item) string { return itm.State }),
+		{
+			name:  columnLatitude,
+			label: labelLatitude,
+			text:  func(itm *item) string { return formatCoordinate(itm.Latitude) },
+			value: func(itm *item) any { return itm.Latitude },
+		},
+		{
+			name:  columnLongitude,
+			label: labelLongitude,
+			text:  func(itm *item) string { return formatCoordinate(itm.Longitude) },
+			value: func(itm *item) any { return itm.Longitude },
+		},
+		stringColumn(columnMapURL, labelMapURL, func(itm *item) string { return itm.MapURL }),
+		{
+			name:  columnFirstSeen,
+			label: labelFirstSeen,
+			text:  func(itm *item) string { return formatTimestamp(itm.FirstSeen) },
+			value: func(itm *item) any { return optionalTime(itm.FirstSeen) },
+		},
+		{
+			name:  columnLastSeen,
+			label: labelLastSeen,
+			text:  func(itm *item) string { return formatTimestamp(itm.LastSeen) },
+			value: func(itm *item) any { return optionalTime(itm.LastSeen) },
+		},
+		{
+			name:  columnVanished,
+			label: labelVanished,
+			text: func(itm *item) string {
+				if itm.Vanished {
+					return "ja"
+				}
+				return ""
+			},
+			value: func(itm *item) any { return itm.Vanished },
+		},
+		stringColumn(columnAuthorityNormalized, labelAuthorityNormalized, func(itm *item) string { return itm.AuthorityNormalized }),
+		{
+			name:  columnDeletedAt,
+			label: labelDeletedAt,
+			text:  func(itm *item) string { return formatTimestamp(itm.DeletedAt) },
+			value: func(itm *item) any { return optionalTime(itm.DeletedAt) },
+		},
+	}
+}
+
+// defaultColumns returns the columns output if none were selected.
+func defaultColumns() []column {
+	return itemColumns()[:numDefaultColumns]
+}
+
+// defaultTableColumns returns the columns of tabular human-readable
+// output if none were selected. Only the summary columns are returned
+// unless details is set.
+func defaultTableColumns(details bool) []column {
+	columns := defaultColumns()
+	if !details {
+		columns = columns[:numSummaryColumns]
+	}
+	return columns
+}
+
+// parseColumns parses a comma-separated list of column names. It
+// returns nil if s is empty.
+func parseColumns(s string) ([]column, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	all := itemColumns()
+	byName := make(map[string]column, len(all))
+	names := make([]string, 0, len(all))
+	for _, c := range all {
+		byName[c.name] = c
+		names = append(names, c.name)
+	}
+
+	var columns []column
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q, valid columns are: %s", name, strings.Join(names, ", "))
+		}
+		columns = append(columns, c)
+	}
+
+	return columns, nil
+}
+
+// formatCoordinate formats v, zero is formatted as an empty string.
+func formatCoordinate(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// itemTitle returns the title of itm in feeds and calendars.
+func itemTitle(itm *item) string {
+	if itm.Vanished {
+		return itm.Name + " (" + strings.ToLower(labelVanished) + ")"
+	}
+	return itm.Name
+}
+
+func columnLabels(columns []column) []string {
+	labels := make([]string, 0, len(columns))
+	for _, c := range columns {
+		labels = append(labels, c.label)
+	}
+	return labels
+}
+
+func columnTexts(itm *item, columns []column) []string {
+	texts := make([]string, 0, len(columns))
+	for _, c := range columns {
+		texts = append(texts, c.text(itm))
+	}
+	return texts
+}
+
+// itemProjection is the JSON representation of an item restricted to
+// the given columns. Keys retain the column order.
+type itemProjection struct {
+	itm     *item
+	columns []column
+}
+
+func (p itemProjection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, c := range p.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(c.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to JSON-encode column name %q: %w", c.name, err)
+		}
+		v, err := json.Marshal(c.value(p.itm))
+		if err != nil {
+			return nil, fmt.Errorf("failed to JSON-encode column %q: %w", c.name, err)
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// jsonValues returns the values to JSON-encode for items. Items are
+// encoded as is if no columns were selected.
+func jsonValues(items []*item, columns []column) []any {
+	values := make([]any, 0, len(items))
+	for _, itm := range items {
+		if columns == nil {
+			values = append(values, itm)
+			continue
+		}
+		values = append(values, itemProjection{
+			itm:     itm,
+			columns: columns,
+		})
+	}
+	return values
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keys of config files which are environment variables rather than flags
+var configEnvKeyRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// configEnvVars returns the environment variables which may be set in a
+// config file as well.
+func configEnvVars() []string {
+	return []string{
+		"DATABASE_URL",
+		"DEBUG",
+		"DESKTOP_NOTIFY",
+		"DESKTOP_NOTIFY_MAX",
+		"DISCORD_WEBHOOK_URL",
+		"GEOCODE_CACHE_FILE",
+		"GEOCODE_CONCURRENCY",
+		"GEOCODE_INTERVAL",
+		"GEOCODER_URL",
+		"KAFKA_BROKERS",
+		"KAFKA_TOPIC",
+		"LMK_ANONYMIZE_SECRET",
+		"LMK_TIMEOUT",
+		"LMK_URL",
+		"LMK_USER_AGENT",
+		"LMK_WEBHOOK_SECRET",
+		"LOG_LEVEL",
+		"MATRIX_ACCESS_TOKEN",
+		"MATRIX_HOMESERVER_URL",
+		"MATRIX_ROOM_ID",
+		"MQTT_BROKER",
+		"MQTT_CLIENT_ID",
+		"MQTT_PASSWORD",
+		"MQTT_TOPIC",
+		"MQTT_USERNAME",
+		"NTFY_PRIORITY",
+		"NTFY_SERVER",
+		"NTFY_TAGS",
+		"NTFY_TOKEN",
+		"NTFY_TOPIC",
+		"SLACK_WEBHOOK_URL",
+		"SMTP_ATTACH_CSV",
+		"SMTP_FROM",
+		"SMTP_HOST",
+		"SMTP_PASSWORD",
+		"SMTP_PORT",
+		"SMTP_TO",
+		"SMTP_USERNAME",
+		"SQLITE_BUSY_TIMEOUT",
+		"SQLITE_FILE",
+		"SQLITE_JOURNAL_MODE",
+		"SQLITE_KEY",
+		"TELEGRAM_API_URL",
+		"TELEGRAM_BOT_TOKEN",
+		"TELEGRAM_CHAT_ID",
+		"TWILIO_ACCOUNT_SID",
+		"TWILIO_API_URL",
+		"TWILIO_AUTH_TOKEN",
+		"TWILIO_FROM",
+		"TWILIO_THRESHOLD",
+		"TWILIO_TO",
+	}
+}
+
+// config holds the settings of a config file. Keys are the names of
+// environment variables, e.g. SQLITE_FILE, or of flags, e.g. since-days,
+// which may be given a list of values if repeatable. The authorities
+// key maps scraped authority names to canonical ones instead, the
+// sources key states to the URLs of their pages.
+type config struct {
+	env   map[string]string
+	flags map[string][]string
+	// Empty if not set, see authorityNormalizer
+	authorities map[string]string
+	// Empty if not set, see configSources
+	sources []source
+}
+
+// loadConfig reads the YAML config file at path. The config is empty if
+// path is.
+func loadConfig(path string) (*config, error) {
+	c := &config{
+		env:   make(map[string]string),
+		flags: make(map[string][]string),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	}
+
+	for k, v := range raw {
+		if k == configAuthoritiesKey {
+			if c.authorities, err = configAuthorities(v); err != nil {
+				return nil, fmt.Errorf("invalid config setting %q: %w", k, err)
+			}
+			continue
+		}
+		if k == configSourcesKey {
+			if c.sources, err = configSources(v); err != nil {
+				return nil, fmt.Errorf("invalid config setting %q: %w", k, err)
+			}
+			continue
+		}
+
+		values, err := configValues(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config setting %q: %w", k, err)
+		}
+
+		if !configEnvKeyRegexp.MatchString(k) {
+			c.flags[k] = values
+			continue
+		}
+		if !slices.Contains(configEnvVars(), k) {
+			return nil, fmt.Errorf("unknown config setting %q", k)
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("invalid config setting %q: expected a single value", k)
+		}
+		c.env[k] = values[0]
+	}
+
+	return c, nil
+}
+
+// configValues returns the scalar v, or the scalars of the list v, as
+// strings.
+func configValues(v any) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, errors.New("missing value")
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			ev, err := configValues(e)
+			if err != nil {
+				return nil, err
+			}
+			if len(ev) != 1 {
+				return nil, errors.New("nested lists aren't supported")
+			}
+			values = append(values, ev...)
+		}
+		return values, nil
+	case map[string]any:
+		return nil, errors.New("mappings aren't supported")
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
+
+// getenv is like the package-level getenv but falls back to the config
+// before falling back to the default.
+func (c *config) getenv(key, fallback string) string {
+	if v, ok := c.env[key]; ok {
+		fallback = v
+	}
+	return getenv(key, fallback)
+}
+
+// applyFlags sets the flags of the config using set. Flags for which
+// skip reports true, e.g. because they were given on the command line,
+// are left untouched. Flags are set in the order of their names.
+func (c *config) applyFlags(
+	isFlag func(name string) bool,
+	skip func(name string) bool,
+	set func(name, value string) error,
+) error {
+	names := make([]string, 0, len(c.flags))
+	for name := range c.flags {
+		if !isFlag(name) {
+			return fmt.Errorf("unknown config setting %q", name)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if skip(name) {
+			continue
+		}
+		for _, v := range c.flags[name] {
+			if err := set(name, v); err != nil {
+				return fmt.Errorf("invalid value %q of config setting %q: %w", v, name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+const (
+	defaultSQLiteFilePath = "./db.sqlite"
+	// Write-ahead logging allows reading, e.g. by the query subcommand,
+	// while another instance writes, and makes concurrent reads faster.
+	defaultSQLiteJournalMode = "wal"
+	// How long to wait for a lock held by another instance before
+	// failing with "database is locked"
+	defaultSQLiteBusyTimeout = 5 * time.Second
+
+	// SQLITE_FILE of an in-memory database, see -memory
+	sqliteMemoryFile = ":memory:"
+	// Unlike :memory:, which is private to each connection, the memdb
+	// VFS shares the database among the connections of the process for
+	// as long as any of them is open
+	sqliteMemoryURI = "file:/lmk?vfs=memdb"
+
+	// Time values are written in this format, see sqliteDSN
+	sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+	// The schema after applying all migrations, used to create the
+	// table when replaying an SQL dump. Keep in sync with migrations,
+	// see TestSQLiteCreateSchemaMatchesMigrations.
+	// The full-text index is left out, it's built when opening the
+	// database.
+	sqliteCreateSchemaStmt = `
+		create table if not exists items (
+			id integer primary key not null,
+			hash text unique not null,
+			authority text not null,
+			published_at text not null,
+			found_at text not null,
+			name text not null,
+			address text not null,
+			reason text not null,
+			legal_basis text not null,
+			info text not null,
+			first_seen text,
+			last_seen text,
+			state text not null default 'bw',
+			published_at_end text,
+			found_at_end text,
+			latitude real,
+			longitude real,
+			street text not null default '',
+			postal_code text not null default '',
+			city text not null default '',
+			authority_normalized text not null default '',
+			deleted_at text
+		) strict;
+		create index if not exists items_published_at on items (published_at);
+		create index if not exists items_authority on items (authority);
+		create index if not exists items_found_at on items (found_at);
+		create index if not exists items_authority_normalized on items (authority_normalized);
+		create index if not exists items_business on items (state, name, address);
+	`
+
+	// Names of the functions registered by registerSQLiteFunctions
+	sqliteFuncLower      = "lmk_lower"
+	sqliteFuncPostalCode = "lmk_postal_code"
+	sqliteCollationDE    = "lmk_de"
+)
+
+// registerSQLiteFunctions makes the Go implementations of the filters
+// available to sqlite, so filtering the database matches filtering
+// scraped items. It must be called once before opening a database.
+func registerSQLiteFunctions() error {
+	stringArg := func(args []driver.Value, i int) string {
+		s, _ := args[i].(string) //nolint:errcheck // Non-strings, e.g. NULL, are treated as an empty string
+		return s
+	}
+
+	var (
+		mu      sync.Mutex
+		regexps = make(map[string]*regexp.Regexp)
+	)
+	for _, fn := range []struct {
+		name  string
+		nargs int32
+		impl  func(args []driver.Value) (driver.Value, error)
+	}{
+		{
+			// Used by sqlite's "x regexp y" operator
+			name:  "regexp",
+			nargs: 2, //nolint:mnd // Pattern and subject
+			impl: func(args []driver.Value) (driver.Value, error) {
+				pattern := stringArg(args, 0)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				re, ok := regexps[pattern]
+				if !ok {
+					var err error
+					if re, err = regexp.Compile(pattern); err != nil {
+						return nil, fmt.Errorf("failed to compile regular expression %q: %w", pattern, err)
+					}
+					regexps[pattern] = re
+				}
+
+				return re.MatchString(stringArg(args, 1)), nil
+			},
+		},
+		{
+			// Other than sqlite's lower, this also lower-cases non-ASCII characters
+			name:  sqliteFuncLower,
+			nargs: 1,
+			impl: func(args []driver.Value) (driver.Value, error) {
+				return strings.ToLower(stringArg(args, 0)), nil
+			},
+		},
+		{
+			name:  sqliteFuncPostalCode,
+			nargs: 1,
+			impl: func(args []driver.Value) (driver.Value, error) {
+				return postalCode(stringArg(args, 0)), nil
+			},
+		},
+	} {
+		if err := sqlite.RegisterDeterministicScalarFunction(
+			fn.name,
+			fn.nargs,
+			func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+				return fn.impl(args)
+			},
+		); err != nil {
+			return fmt.Errorf("failed to register sqlite function %s: %w", fn.name, err)
+		}
+	}
+
+	c := newCollator()
+	if err := sqlite.RegisterCollationUtf8(sqliteCollationDE, c.compare); err != nil {
+		return fmt.Errorf("failed to register sqlite collation %s: %w", sqliteCollationDE, err)
+	}
+
+	return nil
+}
+
+type sqliteConfig struct {
+	file        string
+	journalMode string
+	busyTimeout time.Duration
+}
+
+// inMemory returns whether the database is held in memory, see
+// sqliteMemoryFile.
+func (cfg sqliteConfig) inMemory() bool {
+	return cfg.file == sqliteMemoryFile
+}
+
+// parseSQLiteJournalMode validates the journal mode, see
+// https://www.sqlite.org/pragma.html#pragma_journal_mode.
+func parseSQLiteJournalMode(s string) (string, error) {
+	mode := strings.ToLower(s)
+	switch mode {
+	case "delete", "truncate", "persist", "memory", "wal", "off":
+		return mode, nil
+	}
+
+	return "", fmt.Errorf("unknown sqlite journal mode %q", s)
+}
+
+// sqliteDSN returns the data source name to open the database with. The
+// pragmas are applied to every connection. Time values are written in
+// sqliteTimeFormat which, other than the driver's default, is
+// understood by sqlite's date and time functions.
+func sqliteDSN(cfg sqliteConfig) string {
+	params := url.Values{}
+	params.Set("_time_format", "sqlite")
+	if cfg.journalMode != "" {
+		params.Add("_pragma", "journal_mode("+cfg.journalMode+")")
+	}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.busyTimeout.Milliseconds()))
+
+	if cfg.inMemory() {
+		return sqliteMemoryURI + "&" + params.Encode()
+	}
+	return cfg.file + "?" + params.Encode()
+}
+
+// openSQLite opens the sqlite database, creating and migrating it as
+// needed.
+func openSQLite(ctx context.Context, l *slog.Logger, cfg sqliteConfig) (*sqlStorage, error) {
+	db, err := sql.Open("sqlite", sqliteDSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	if err := migrateDB(ctx, l, db, sqliteMigrator()); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+
+	return &sqlStorage{
+		db:                 db,
+		name:               "sqlite",
+		hasFilterFunctions: true,
+		hasFullTextIndex:   true,
+		// Stored as text in sqliteTimeFormat, publication dates are UTC
+		publishedMonthExpr: "substr(published_at, 1, 7)",
+		// The other times are of the local offset when stored, which may
+		// change, e.g. with daylight saving time
+		timeExpr: "julianday(%s)",
+	}, nil
+}
+
+// openExistingSQLite opens the sqlite database, which must exist unless
+// it's in memory. An in-memory database is created and migrated like a
+// new one, if it isn't open yet.
+func openExistingSQLite(ctx context.Context, l *slog.Logger, cfg sqliteConfig) (*sqlStorage, error) {
+	if cfg.inMemory() {
+		return openSQLite(ctx, l, cfg)
+	}
+	if _, err := os.Stat(cfg.file); err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	return openSQLite(ctx, l, cfg)
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	selectSchemaVersionStmt = `
+		select coalesce(max(version), 0) from schema_version;
+	`
+	insertSchemaVersionStmt = `
+		insert into schema_version (version, applied_at) values ($1, $2);
+	`
+
+	selectAddressesStmt = `
+		select id, address from items;
+	`
+	updateAddressStmt = `
+		update items set street = $1, postal_code = $2, city = $3 where id = $4;
+	`
+
+	selectItemIdentitiesStmt = `
+		select id, state, authority, published_at, found_at, name, address, reason, legal_basis, info
+		from items order by id;
+	`
+	normalizeStoredAuthoritiesStmt = `
+		update items set authority_normalized = authority where authority_normalized = '';
+	`
+
+	updateItemHashStmt = `
+		update items set hash = $1 where id = $2;
+	`
+	deleteItemStmt = `
+		delete from items where id = $1;
+	`
+
+	sqliteCreateSchemaVersionStmt = `
+		create table if not exists schema_version (
+			version integer primary key not null,
+			applied_at text not null
+		) strict;
+	`
+	sqliteHasColumnStmt = `
+		select count(*) from pragma_table_info(?) where name = ?;
+	`
+)
+
+// migration upgrades the database schema by one version.
+type migration func(ctx context.Context, tx *sql.Tx) error
+
+// migrator holds the schema migrations of a database system in the
+// order they must be applied. The schema version of a database is the
+// number of migrations applied to it. Migrations must never be changed
+// once released, append a new one instead.
+type migrator struct {
+	createSchemaVersionStmt string
+	migrations              []migration
+}
+
+func sqliteMigrator() migrator {
+	return migrator{
+		createSchemaVersionStmt: sqliteCreateSchemaVersionStmt,
+		migrations:              sqliteMigrations(),
+	}
+}
+
+func sqliteMigrations() []migration {
+	return []migration{
+		// 1
+		execMigration(`
+			create table if not exists items (
+				id integer primary key not null,
+				hash text unique not null,
+				authority text not null,
+				published_at text not null,
+				found_at text not null,
+				name text not null,
+				address text not null,
+				reason text not null,
+				legal_basis text not null,
+				info text not null
+			) strict;
+		`),
+		// 2, items stored before have it unset
+		addColumnMigration("items", "first_seen", "text"),
+		// 3, items stored before have it unset
+		addColumnMigration("items", "last_seen", "text"),
+		// 4, used by the query filters and sort orders
+		execMigration(`
+			create index if not exists items_published_at on items (published_at);
+			create index if not exists items_authority on items (authority);
+			create index if not exists items_found_at on items (found_at);
+		`),
+		// 5, only Baden-Württemberg was scraped before
+		addColumnMigration("items", "state", "text not null default 'bw'"),
+		// 6, unset for single dates
+		addColumnMigration("items", "published_at_end", "text"),
+		// 7, unset for single dates
+		addColumnMigration("items", "found_at_end", "text"),
+		// 8, unset unless geocoded
+		addColumnMigration("items", "latitude", "real"),
+		// 9, unset unless geocoded
+		addColumnMigration("items", "longitude", "real"),
+		// 10
+		addColumnMigration("items", "street", "text not null default ''"),
+		// 11
+		addColumnMigration("items", "postal_code", "text not null default ''"),
+		// 12
+		addColumnMigration("items", "city", "text not null default ''"),
+		// 13
+		parseAddressesMigration,
+		// 14
+		rehashItemsMigration,
+		// 15, the full-text index used by the search subcommand, kept in
+		// sync by triggers
+		execMigration(
+			`create virtual table if not exists items_fts using fts5(
+				name, address, reason, info,
+				content = 'items',
+				content_rowid = 'id'
+			);`,
+			`create trigger if not exists items_fts_insert after insert on items begin
+				insert into items_fts (rowid, name, address, reason, info)
+				values (new.id, new.name, new.address, new.reason, new.info);
+			end;`,
+			`create trigger if not exists items_fts_delete after delete on items begin
+				insert into items_fts (items_fts, rowid, name, address, reason, info)
+				values ('delete', old.id, old.name, old.address, old.reason, old.info);
+			end;`,
+			`create trigger if not exists items_fts_update after update of name, address, reason, info on items begin
+				insert into items_fts (items_fts, rowid, name, address, reason, info)
+				values ('delete', old.id, old.name, old.address, old.reason, old.info);
+				insert into items_fts (rowid, name, address, reason, info)
+				values (new.id, new.name, new.address, new.reason, new.info);
+			end;`,
+			`insert into items_fts (items_fts) values ('rebuild');`,
+		),
+		// 16
+		addColumnMigration("items", "authority_normalized", "text not null default ''"),
+		// 17, items stored before weren't normalized
+		execMigration(normalizeStoredAuthoritiesStmt),
+		// 18, used by the stats subcommand
+		execMigration(`create index if not exists items_authority_normalized on items (authority_normalized);`),
+		// 19, see -history
+		execMigration(
+			`create table if not exists history (
+				id integer primary key not null,
+				state text not null,
+				name text not null,
+				address text not null,
+				field text not null,
+				old_value text not null,
+				new_value text not null,
+				changed_at text not null
+			) strict;`,
+			`create index if not exists history_business on history (state, name, address);`,
+			`create index if not exists items_business on items (state, name, address);`,
+		),
+		// 20, see -soft-delete
+		addColumnMigration("items", "deleted_at", "text"),
+	}
+}
+
+// parseAddressesMigration sets the address parts of the items stored
+// before they were parsed.
+func parseAddressesMigration(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, selectAddressesStmt)
+	if err != nil {
+		return fmt.Errorf("failed to query addresses: %w", err)
+	}
+
+	type address struct {
+		id      int64
+		address string
+	}
+	var addresses []address
+	for rows.Next() {
+		var a address
+		if err := rows.Scan(&a.id, &a.address); err != nil {
+			return errors.Join(fmt.Errorf("failed to scan address: %w", err), rows.Close())
+		}
+		addresses = append(addresses, a)
+	}
+	// Updating while iterating the rows isn't supported by all drivers
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return fmt.Errorf("failed to iterate addresses: %w", err)
+	}
+
+	for _, a := range addresses {
+		street, postalCode, city := parseAddress(a.address)
+		if _, err := tx.ExecContext(ctx, updateAddressStmt, street, postalCode, city, a.id); err != nil {
+			return fmt.Errorf("failed to update address: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rehashItemsMigration replaces the hashes of the items stored before
+// with the ones of itemHash. The hashes were of the gob-encoded items
+// including their date strings before, items which are identical
+// without those are duplicates now, all but the first stored of them
+// are deleted.
+func rehashItemsMigration(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, selectItemIdentitiesStmt)
+	if err != nil {
+		return fmt.Errorf("failed to query items: %w", err)
+	}
+
+	type storedItem struct {
+		id   int64
+		hash string
+	}
+	var items []storedItem
+	for rows.Next() {
+		var (
+			id                   int64
+			itm                  item
+			publishedAt, foundAt dbTime
+		)
+		if err := rows.Scan(
+			&id,
+			&itm.State,
+			&itm.Authority,
+			&publishedAt,
+			&foundAt,
+			&itm.Name,
+			&itm.Address,
+			&itm.Reason,
+			&itm.LegalBasis,
+			&itm.Info,
+		); err != nil {
+			return errors.Join(fmt.Errorf("failed to scan item: %w", err), rows.Close())
+		}
+		itm.PublishedAt, itm.FoundAt = publishedAt.Time, foundAt.Time
+		items = append(items, storedItem{id, itemHash(&itm)})
+	}
+	// Updating while iterating the rows isn't supported by all drivers
+	if err := errors.Join(rows.Err(), rows.Close()); err != nil {
+		return fmt.Errorf("failed to iterate items: %w", err)
+	}
+
+	seen := make(map[string]bool, len(items))
+	for _, itm := range items {
+		if seen[itm.hash] {
+			if _, err := tx.ExecContext(ctx, deleteItemStmt, itm.id); err != nil {
+				return fmt.Errorf("failed to delete duplicate item: %w", err)
+			}
+			continue
+		}
+		seen[itm.hash] = true
+
+		if _, err := tx.ExecContext(ctx, updateItemHashStmt, itm.hash, itm.id); err != nil {
+			return fmt.Errorf("failed to update item hash: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func execMigration(stmts ...string) migration {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to exec migration statement: %w", err)
+			}
+		}
+		return nil
+	}
+}
+
+// addColumnMigration adds the column to an sqlite table unless it
+// already exists, which is the case for databases created before schema
+// versions were tracked.
+func addColumnMigration(table, column, definition string) migration {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		var n int
+		if err := tx.QueryRowContext(ctx, sqliteHasColumnStmt, table, column).Scan(&n); err != nil {
+			return fmt.Errorf("failed to check for column %s.%s: %w", table, column, err)
+		}
+		if n > 0 {
+			return nil
+		}
+
+		return execMigration("alter table "+table+" add column "+column+" "+definition+";")(ctx, tx)
+	}
+}
+
+// migrateDB applies all pending migrations in a single transaction.
+func migrateDB(ctx context.Context, l *slog.Logger, db *sql.DB, m migrator) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration transaction: %w", err)
+	}
+
+	from, to, err := applyMigrations(ctx, tx, m)
+	if err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration transaction: %w", err)
+	}
+
+	if from != to {
+		l.InfoContext(
+			ctx,
+			"successfully migrated database",
+			"from", from,
+			"to", to,
+		)
+	}
+
+	return nil
+}
+
+// applyMigrations applies the migrations the database is missing. It
+// returns the schema versions before and after.
+func applyMigrations(ctx context.Context, tx *sql.Tx, m migrator) (int, int, error) {
+	if _, err := tx.ExecContext(ctx, m.createSchemaVersionStmt); err != nil {
+		return 0, 0, fmt.Errorf("failed to create schema version table: %w", err)
+	}
+
+	var from int
+	if err := tx.QueryRowContext(ctx, selectSchemaVersionStmt).Scan(&from); err != nil {
+		return 0, 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	ms := m.migrations
+	if from > len(ms) {
+		return 0, 0, fmt.Errorf("database schema version %d is newer than the supported version %d", from, len(ms))
+	}
+
+	now := time.Now()
+	for i, migrate := range ms[from:] {
+		version := from + i + 1
+		if err := migrate(ctx, tx); err != nil {
+			return 0, 0, fmt.Errorf("failed to migrate database to schema version %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, insertSchemaVersionStmt, version, now); err != nil {
+			return 0, 0, fmt.Errorf("failed to set schema version %d: %w", version, err)
+		}
+	}
+
+	return from, len(ms), nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const postgresCreateSchemaVersionStmt = `
+	create table if not exists schema_version (
+		version integer primary key not null,
+		applied_at timestamptz not null
+	);
+`
+
+// openPostgres opens the Postgres database identified by the given URL
+// or DSN, migrating it as needed.
+func openPostgres(ctx context.Context, l *slog.Logger, databaseURL string) (*sqlStorage, error) {
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres database: %w", err)
+	}
+
+	if err := migrateDB(ctx, l, db, postgresMigrator()); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+
+	return &sqlStorage{
+		db:   db,
+		name: "postgres",
+		// The functions backing the filters are registered with sqlite
+		// only, filters are applied in Go instead.
+		hasFilterFunctions: false,
+		hasFullTextIndex:   false,
+		publishedMonthExpr: "to_char(published_at at time zone 'UTC', 'YYYY-MM')",
+		// Compared by their instant as timestamptz
+		timeExpr: "%s",
+	}, nil
+}
+
+func postgresMigrator() migrator {
+	return migrator{
+		createSchemaVersionStmt: postgresCreateSchemaVersionStmt,
+		migrations: []migration{
+			// 1
+			execMigration(
+				`
+				create table if not exists items (
+					id bigint generated always as identity primary key,
+					hash text unique not null,
+					authority text not null,
+					published_at timestamptz not null,
+					found_at timestamptz not null,
+					name text not null,
+					address text not null,
+					reason text not null,
+					legal_basis text not null,
+					info text not null,
+					first_seen timestamptz,
+					last_seen timestamptz
+				);
+				`,
+				`create index if not exists items_published_at on items (published_at);`,
+				`create index if not exists items_authority on items (authority);`,
+				`create index if not exists items_found_at on items (found_at);`,
+			),
+			// 2, only Baden-Württemberg was scraped before
+			execMigration(`alter table items add column if not exists state text not null default 'bw';`),
+			// 3, unset for single dates
+			execMigration(
+				`alter table items add column if not exists published_at_end timestamptz;`,
+				`alter table items add column if not exists found_at_end timestamptz;`,
+			),
+			// 4, unset unless geocoded
+			execMigration(
+				`alter table items add column if not exists latitude double precision;`,
+				`alter table items add column if not exists longitude double precision;`,
+			),
+			// 5
+			execMigration(
+				`alter table items add column if not exists street text not null default '';`,
+				`alter table items add column if not exists postal_code text not null default '';`,
+				`alter table items add column if not exists city text not null default '';`,
+			),
+			// 6
+			parseAddressesMigration,
+			// 7
+			rehashItemsMigration,
+			// 8, items stored before weren't normalized
+			execMigration(
+				`alter table items add column if not exists authority_normalized text not null default '';`,
+				normalizeStoredAuthoritiesStmt,
+				`create index if not exists items_authority_normalized on items (authority_normalized);`,
+			),
+			// 9, see -history
+			execMigration(
+				`
+				create table if not exists history (
+					id bigint generated always as identity primary key,
+					state text not null,
+					name text not null,
+					address text not null,
+					field text not null,
+					old_value text not null,
+					new_value text not null,
+					changed_at timestamptz not null
+				);
+				`,
+				`create index if not exists history_business on history (state, name, address);`,
+				`create index if not exists items_business on items (state, name, address);`,
+			),
+			// 10, see -soft-delete
+			execMigration(
+				`alter table items add column if not exists deleted_at timestamptz;`,
+			),
+		},
+	}
+}
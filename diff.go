@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// diffSection is a section of the output in diff mode.
+type diffSection struct {
+	title string
+	items []*item
+}
+
+// diffItems returns the sections of the scraped items compared to the
+// previous scrape: the items never stored before, the stored ones which
+// reappeared and those which vanished. newItems are the scraped items
+// which haven't been stored before, previous the items seen by the
+// previous scrape, see storage.lastSeenItems.
+func diffItems(scraped, newItems, previous []*item) []diffSection {
+	isNew := make(map[*item]struct{}, len(newItems))
+	for _, itm := range newItems {
+		isNew[itm] = struct{}{}
+	}
+	seenBefore := make(map[string]struct{}, len(previous))
+	for _, itm := range previous {
+		seenBefore[itemHash(itm)] = struct{}{}
+	}
+
+	var reappeared []*item
+	seen := make(map[string]struct{}, len(scraped))
+	for _, itm := range scraped {
+		hash := itemHash(itm)
+		seen[hash] = struct{}{}
+
+		if _, ok := isNew[itm]; ok {
+			continue
+		}
+		if _, ok := seenBefore[hash]; !ok {
+			reappeared = append(reappeared, itm)
+		}
+	}
+
+	var vanished []*item
+	for _, itm := range previous {
+		if _, ok := seen[itemHash(itm)]; !ok {
+			itm.Vanished = true
+			vanished = append(vanished, itm)
+		}
+	}
+
+	return []diffSection{
+		{labelDiffNew, newItems},
+		{labelDiffReappeared, reappeared},
+		{labelVanished, vanished},
+	}
+}
+
+// renderDiff prints each section with a header line followed by its
+// items, if any, in the output format.
+func renderDiff(
+	ctx context.Context,
+	l *slog.Logger,
+	sections []diffSection,
+	opts outputOptions,
+) error {
+	return writeOutput(opts.file, func(w io.Writer) error {
+		for i, s := range sections {
+			header := fmt.Sprintf("# %s (%d)\n", s.title, len(s.items))
+			if i > 0 {
+				header = "\n" + header
+			}
+			if _, err := io.WriteString(w, header); err != nil {
+				return fmt.Errorf("failed to print section header: %w", err)
+			}
+			if len(s.items) == 0 {
+				continue
+			}
+			if err := render(ctx, l, w, s.items, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
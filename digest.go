@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Europe/Berlin must be known even without a system tz database
+)
+
+const (
+	digestFileName        = "digest.gob"
+	digestFilePermissions = 0o600
+
+	// Of the digest schedule, see -digest
+	digestLocation     = "Europe/Berlin"
+	digestDailyPrefix  = "daily@"
+	digestTimeOfDayFmt = "15:04"
+)
+
+// digestSchedule is the local time of day a digest is sent at.
+type digestSchedule struct {
+	hour, minute int
+	loc          *time.Location
+}
+
+// parseDigestSchedule parses s of the form daily@HH:MM, a time of day in
+// digestLocation.
+func parseDigestSchedule(s string) (digestSchedule, error) {
+	tod, ok := strings.CutPrefix(s, digestDailyPrefix)
+	if !ok {
+		return digestSchedule{}, fmt.Errorf("invalid digest schedule %q, must be of the form %sHH:MM", s, digestDailyPrefix)
+	}
+	t, err := time.Parse(digestTimeOfDayFmt, tod)
+	if err != nil {
+		return digestSchedule{}, fmt.Errorf("invalid digest time of day %q, must be HH:MM", tod)
+	}
+	loc, err := time.LoadLocation(digestLocation)
+	if err != nil {
+		return digestSchedule{}, fmt.Errorf("failed to load digest location: %w", err)
+	}
+
+	return digestSchedule{
+		hour:   t.Hour(),
+		minute: t.Minute(),
+		loc:    loc,
+	}, nil
+}
+
+// next returns the first time of the schedule after t.
+func (s digestSchedule) next(t time.Time) time.Time {
+	lt := t.In(s.loc)
+	at := time.Date(lt.Year(), lt.Month(), lt.Day(), s.hour, s.minute, 0, 0, s.loc)
+	if !at.After(t) {
+		// Rather than adding 24 hours, which is off on DST changes
+		at = time.Date(lt.Year(), lt.Month(), lt.Day()+1, s.hour, s.minute, 0, 0, s.loc)
+	}
+	return at
+}
+
+// digestState is persisted by digestNotifier so a restart doesn't lose
+// the pending items. gob-encoded as the date strings of the items aren't
+// part of their JSON encoding.
+type digestState struct {
+	// Not sent yet, in the order they were notified about
+	Items []*item
+	// When the last digest was sent, zero if never
+	SentAt time.Time
+}
+
+// digestNotifier collects the items it's notified about and sends them
+// as a single digest using its notifier on schedule, see run.
+type digestNotifier struct {
+	l        *slog.Logger
+	n        notifier
+	path     string
+	schedule digestSchedule
+
+	mu    sync.Mutex
+	state digestState
+	// Hashes of the pending items, see itemHash
+	hashes map[string]struct{}
+}
+
+// newDigestNotifier returns a notifier sending digests using n, loading
+// the pending items saved to path before.
+func newDigestNotifier(l *slog.Logger, n notifier, path string, schedule digestSchedule) (*digestNotifier, error) {
+	d := &digestNotifier{
+		l:        l,
+		n:        n,
+		path:     path,
+		schedule: schedule,
+		hashes:   make(map[string]struct{}),
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digest file: %w", err)
+	}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&d.state); err != nil {
+		return nil, fmt.Errorf("failed to decode digest file: %w", err)
+	}
+	for _, itm := range d.state.Items {
+		d.hashes[itemHash(itm)] = struct{}{}
+	}
+
+	return d, nil
+}
+
+func (d *digestNotifier) Name() string {
+	return d.n.Name() + " digest"
+}
+
+// Notify adds the items to the next digest rather than notifying about
+// them right away.
+func (d *digestNotifier) Notify(_ context.Context, items []*item) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var added int
+	for _, itm := range items {
+		h := itemHash(itm)
+		if _, ok := d.hashes[h]; ok {
+			continue
+		}
+		d.hashes[h] = struct{}{}
+		d.state.Items = append(d.state.Items, itm)
+		added++
+	}
+	if err := d.save(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w, added %d items to the digest", errNotifySkipped, added)
+}
+
+// run sends the pending items on schedule until ctx is done. A digest
+// due while not running, e.g. restarting, is sent right away. A failed
+// digest is logged and its items are sent with the next one.
+func (d *digestNotifier) run(ctx context.Context) {
+	d.mu.Lock()
+	after := d.state.SentAt
+	d.mu.Unlock()
+	if after.IsZero() {
+		after = time.Now()
+	}
+
+	for {
+		at := d.schedule.next(after)
+		d.l.DebugContext(ctx, "waiting for next digest", "next", at.Format(timestampFormat))
+		if err := waitContext(ctx, time.Until(at)); err != nil {
+			return
+		}
+
+		if err := d.send(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			d.l.ErrorContext(ctx, err.Error(), "notifier", d.Name())
+		}
+		after = time.Now()
+	}
+}
+
+// send sends the pending items, if any, and removes them once sent.
+func (d *digestNotifier) send(ctx context.Context) error {
+	d.mu.Lock()
+	items := d.state.Items
+	d.mu.Unlock()
+
+	if len(items) > 0 {
+		if err := d.n.Notify(ctx, items); err != nil && !errors.Is(err, errNotifySkipped) {
+			return err
+		}
+		d.l.InfoContext(ctx, "sent digest", "notifier", d.Name(), "items", len(items))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Items may have been added while sending
+	d.state.Items = d.state.Items[len(items):]
+	for _, itm := range items {
+		delete(d.hashes, itemHash(itm))
+	}
+	d.state.SentAt = time.Now()
+
+	return d.save()
+}
+
+// save persists the state, d.mu must be held.
+func (d *digestNotifier) save() error {
+	if err := writeFileAtomic(d.path, digestFilePermissions, func(w io.Writer) error {
+		if err := gob.NewEncoder(w).Encode(d.state); err != nil {
+			return fmt.Errorf("failed to encode digest file: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save digest file: %w", err)
+	}
+
+	return nil
+}
+
+// withDigest calls cmd, e.g. watching, while sending the digests of d.
+func withDigest(ctx context.Context, d *digestNotifier, cmd func() error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.run(ctx)
+	}()
+
+	err := cmd()
+	cancel()
+	<-done
+
+	return err
+}
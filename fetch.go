@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFetchAttempts = 3
+
+	// The backoff doubles with every attempt
+	fetchBackoffBase = 500 * time.Millisecond
+	fetchBackoffMax  = 10 * time.Second
+
+	// Between requests to the source's server, conservative as the
+	// pages change a few times a day at most, see -fetch-interval
+	defaultFetchInterval = time.Second
+
+	// Makes readPageFile read from stdin
+	pageFileStdin = "-"
+)
+
+// statusError is returned for responses other than 200 OK.
+type statusError struct {
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return "unexpected status " + e.status
+}
+
+// fetchPage gets the page, making up to opts.attempts requests of
+// opts.timeout each. Only network errors, timeouts and server errors are
+// retried. The attempts along with the backoffs between them take
+// fetchTimeout at most, the waits for opts.limiter don't count.
+func fetchPage(
+	ctx context.Context,
+	l *slog.Logger,
+	client *http.Client,
+	opts loadOptions,
+) ([]byte, pageValidators, error) {
+	remaining := fetchTimeout(opts)
+	for attempt := 1; ; attempt++ {
+		if err := opts.limiter.wait(ctx); err != nil {
+			return nil, pageValidators{}, err
+		}
+
+		start := time.Now()
+		body, validators, err := fetchPageOnce(ctx, l, client, opts, min(opts.timeout, remaining))
+		if err == nil {
+			return body, validators, nil
+		}
+		if attempt >= opts.attempts || !isRetryable(err) || ctx.Err() != nil {
+			return nil, pageValidators{}, err
+		}
+
+		d := fetchBackoff(attempt)
+		if remaining -= time.Since(start) + d; remaining <= 0 {
+			return nil, pageValidators{}, err
+		}
+		l.WarnContext(
+			ctx,
+			"failed to fetch page, retrying",
+			"err", err,
+			"attempt", attempt,
+			"backoff", d.String(),
+		)
+		if err := waitContext(ctx, d); err != nil {
+			return nil, pageValidators{}, fmt.Errorf("failed to wait for retry: %w", err)
+		}
+	}
+}
+
+// fetchPageOnce gets the page along with its validators, failing if
+// that takes longer than timeout. It fails with errPageNotModified if
+// opts.validators are set and still match the page.
+func fetchPageOnce(
+	ctx context.Context,
+	l *slog.Logger,
+	client *http.Client,
+	opts loadOptions,
+	timeout time.Duration,
+) ([]byte, pageValidators, error) {
+	// Covers reading the body as well, unlike a deadline of the client
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, opts.url, http.NoBody)
+	if err != nil {
+		return nil, pageValidators{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", opts.userAgent)
+	opts.validators.setConditional(req)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, pageValidators{}, fmt.Errorf("failed to get: %w", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close body: %w", err).Error())
+		}
+	}()
+
+	if res.StatusCode == http.StatusNotModified {
+		return nil, pageValidators{}, errPageNotModified
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, pageValidators{}, fmt.Errorf("failed to get: %w", &statusError{
+			code:   res.StatusCode,
+			status: res.Status,
+		})
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, pageValidators{}, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	return body, validatorsFromResponse(res), nil
+}
+
+// isRetryable reports whether a request failing with err may succeed
+// when retried. Client errors are permanent, everything else, e.g. a
+// timeout or a reset connection, is assumed to be transient. An
+// unmodified page is no failure at all.
+func isRetryable(err error) bool {
+	if errors.Is(err, errPageNotModified) {
+		return false
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// fetchTimeout returns how long fetching a page may take at most, all of
+// opts.attempts timing out and the longest backoffs between them.
+func fetchTimeout(opts loadOptions) time.Duration {
+	d := time.Duration(max(opts.attempts, 1)) * opts.timeout
+	for attempt := 1; attempt < opts.attempts; attempt++ {
+		d += maxFetchBackoff(attempt)
+	}
+	return d
+}
+
+// fetchBackoff returns the delay before the given attempt is retried.
+// Half of it is random to keep concurrent clients from retrying in
+// lockstep.
+func fetchBackoff(attempt int) time.Duration {
+	d := maxFetchBackoff(attempt)
+	return d/2 + rand.N(d/2+1) //nolint:gosec // Jitter needn't be cryptographically secure
+}
+
+// maxFetchBackoff returns the longest delay before the given attempt is
+// retried, see fetchBackoff.
+func maxFetchBackoff(attempt int) time.Duration {
+	if shift := attempt - 1; shift < 16 { //nolint:mnd // Avoid overflowing, way beyond the maximum anyway
+		return min(fetchBackoffBase<<shift, fetchBackoffMax)
+	}
+	return fetchBackoffMax
+}
+
+// waitContext waits for d or until ctx is done.
+func waitContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck // Wrapped by the callers
+	case <-t.C:
+		return nil
+	}
+}
+
+// fetchLimiter spaces requests at least interval apart, a token bucket
+// holding a single token. It is safe for concurrent use and shared by all
+// scrapes, e.g. when watching.
+type fetchLimiter struct {
+	interval time.Duration
+
+	mu sync.Mutex
+	// When the next request may be made
+	next time.Time
+}
+
+// newFetchLimiter returns a limiter of interval, nil if interval is zero
+// which doesn't limit requests at all.
+func newFetchLimiter(interval time.Duration) *fetchLimiter {
+	if interval <= 0 {
+		return nil
+	}
+	return &fetchLimiter{interval: interval}
+}
+
+// wait waits until the next request may be made or ctx is done.
+func (fl *fetchLimiter) wait(ctx context.Context) error {
+	if fl == nil {
+		return nil
+	}
+
+	fl.mu.Lock()
+	now := time.Now()
+	at := fl.next
+	if at.Before(now) {
+		at = now
+	}
+	fl.next = at.Add(fl.interval)
+	fl.mu.Unlock()
+
+	if err := waitContext(ctx, at.Sub(now)); err != nil {
+		return fmt.Errorf("failed to wait for fetch interval: %w", err)
+	}
+	return nil
+}
+
+// readPageFile reads a page saved before, e.g. a snapshot, or from
+// stdin if path is "-". Stdin is read until EOF but left open.
+func readPageFile(path string) ([]byte, error) {
+	if path == pageFileStdin {
+		body, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page from stdin: %w", err)
+		}
+		return body, nil
+	}
+
+	body, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("page file %q does not exist", path)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read page file: %w", err)
+	}
+	return body, nil
+}
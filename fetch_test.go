@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sourcePage returns a page of src listing the rows, each holding the
+// texts of its columns, linking to next if it isn't empty.
+func sourcePage(src *source, rows [][]string, next string) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><table id="consumerInfoTable"><thead><tr>`)
+	for _, c := range src.columns {
+		b.WriteString("<th><p>" + html.EscapeString(c.label) + "</p></th>")
+	}
+	b.WriteString("</tr></thead><tbody>")
+	for _, row := range rows {
+		b.WriteString("<tr>")
+		for _, text := range row {
+			b.WriteString("<td>" + html.EscapeString(text) + "</td>")
+		}
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table>")
+	if next != "" {
+		b.WriteString(`<a rel="next" href="` + html.EscapeString(next) + `">Weiter</a>`)
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+func TestScrapeSourceDeadline(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+
+	src, err := lookupSource("bw")
+	if err != nil {
+		t.Fatalf("failed to look up source: %v", err)
+	}
+
+	// Each page is slow and links to another one, far more of them than
+	// fit into the scrape timeout
+	const pageDelay = 50 * time.Millisecond
+	var pages atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := int(pages.Add(1))
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(pageDelay):
+		}
+		row := []string{
+			"Stadt Stuttgart",
+			"01.03.2024",
+			"Metzgerei " + strconv.Itoa(page),
+			"Königstraße 10, 70173 Stuttgart",
+			"20.02.2024",
+			"Kühlkette unterbrochen",
+			"§ 11 LFGB",
+			"",
+		}
+		if _, err := fmt.Fprint(w, sourcePage(src, [][]string{row}, "?page="+strconv.Itoa(page+1))); err != nil {
+			t.Errorf("failed to write page: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	const scrapeTimeout = 300 * time.Millisecond
+	start := time.Now()
+	_, err = scrapeSource(ctx, l, src, loadOptions{
+		url:           srv.URL,
+		timeout:       10 * time.Second,
+		scrapeTimeout: scrapeTimeout,
+		attempts:      3,
+	}, runOptions{})
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want the scrape timing out", err)
+	}
+	// Far below the pages along with their retries, see fetchTimeout
+	if elapsed > scrapeTimeout+time.Second {
+		t.Errorf("scrape took %s, want it cut off after %s", elapsed, scrapeTimeout)
+	}
+	if n := pages.Load(); n < 2 || n >= sourceMaxPages {
+		t.Errorf("got %d pages requested, want the scrape cut off while following pagination", n)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic calls write with a temporary file next to path and
+// renames it to path afterwards, so readers never observe a partially
+// written file.
+func writeFileAtomic(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	return createFileAtomic(path, perm, func(f *os.File) error { return write(f) })
+}
+
+// createFileAtomic is like writeFileAtomic but passes the empty temporary
+// file itself, e.g. for its name.
+func createFileAtomic(path string, perm os.FileMode, create func(f *os.File) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %q: %w", path, err)
+	}
+	tmpPath := f.Name()
+
+	if err := func() error {
+		if err := create(f); err != nil {
+			return errors.Join(err, f.Close())
+		}
+		if err := f.Chmod(perm); err != nil {
+			return errors.Join(fmt.Errorf("failed to chmod %q: %w", tmpPath, err), f.Close())
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close %q: %w", tmpPath, err)
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			return fmt.Errorf("failed to rename %q to %q: %w", tmpPath, path, err)
+		}
+		return nil
+	}(); err != nil {
+		return errors.Join(err, os.Remove(tmpPath))
+	}
+
+	return nil
+}
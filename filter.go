@@ -0,0 +1,434 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// Matches a leading flag group such as (?i) or (?-i:…)
+	regexpFlagsPrefix = regexp.MustCompile(`^\(\?[imsU-]+[:)]`)
+	// Matches a German five-digit postal code
+	postalCodeRegexp = regexp.MustCompile(`(?:^|\D)(\d{5})(?:\D|$)`)
+)
+
+// dateRange is an inclusive range of dates. A zero bound is unbounded.
+type dateRange struct {
+	after  time.Time
+	before time.Time
+}
+
+func (r dateRange) isSet() bool {
+	return !r.after.IsZero() || !r.before.IsZero()
+}
+
+func (r dateRange) validate() error {
+	if !r.after.IsZero() && !r.before.IsZero() && r.after.After(r.before) {
+		return fmt.Errorf(
+			"start of date range %s is after its end %s",
+			r.after.Format(timeFormat),
+			r.before.Format(timeFormat),
+		)
+	}
+	return nil
+}
+
+// contains reports whether t is within the range. A zero t, i.e. a date
+// which couldn't be parsed, is never contained in a set range.
+func (r dateRange) contains(t time.Time) bool {
+	if !r.isSet() {
+		return true
+	}
+	if t.IsZero() {
+		return false
+	}
+	return (r.after.IsZero() || !t.Before(r.after)) &&
+		(r.before.IsZero() || !t.After(r.before))
+}
+
+type filter struct {
+	// Lower-cased, matched against the whole authority name
+	authority string
+	published dateRange
+	// Items with multiple inspection dates are matched by the first
+	// one only, see texts2item.
+	found dateRange
+	name  *regexp.Regexp
+	// Lower-cased, the reason must contain any of them
+	reasonKeywords []string
+	// Lower-cased, items with an empty legal basis never match
+	legalBasis string
+	// Lower-cased, matched against the city, or against the whole
+	// address if it couldn't be parsed
+	city string
+	// Items without a postal code in their address never match
+	postalCodePrefix string
+	// Lower-cased, the item must be of any of them
+	states []string
+	// Collapse the matching items reprinted under another date, see
+	// dedupItems
+	dedup bool
+	// Match soft-deleted items as well, see -soft-delete
+	includeDeleted bool
+
+	// Orders the matching items, keep their order if zero
+	order itemOrder
+	// Number of matching items to skip
+	offset int
+	// Maximum number of items to return, no limit if <= 0
+	limit int
+}
+
+// filterOptions holds the filter settings as given, e.g. as flags.
+type filterOptions struct {
+	// Dates in timeFormat, empty if unset
+	publishedAfter  string
+	publishedBefore string
+	// Combined with publishedAfter the later date wins
+	sinceDays   int
+	foundAfter  string
+	foundBefore string
+
+	authority        string
+	reasonKeywords   []string
+	legalBasis       string
+	city             string
+	postalCodePrefix string
+	states           []string
+	nameRegex        string
+	dedup            bool
+	includeDeleted   bool
+
+	sortField string
+	sortDesc  bool
+	offset    int
+	limit     int
+}
+
+// newFilter parses and validates opts. sinceDays counts back from now.
+func newFilter(opts filterOptions, now time.Time) (*filter, error) {
+	var f filter
+	for _, d := range []struct {
+		dst *time.Time
+		s   string
+	}{
+		{&f.published.after, opts.publishedAfter},
+		{&f.published.before, opts.publishedBefore},
+		{&f.found.after, opts.foundAfter},
+		{&f.found.before, opts.foundBefore},
+	} {
+		t, err := parseDate(d.s)
+		if err != nil {
+			return nil, err
+		}
+		*d.dst = t
+	}
+	if opts.sinceDays > 0 {
+		if since := daysAgo(now, opts.sinceDays); since.After(f.published.after) {
+			f.published.after = since
+		}
+	}
+
+	nameRe, err := compileFilterRegexp(opts.nameRegex)
+	if err != nil {
+		return nil, err
+	}
+	f.name = nameRe
+	f.reasonKeywords = lowerAll(opts.reasonKeywords)
+	f.authority = strings.ToLower(opts.authority)
+	f.legalBasis = strings.ToLower(opts.legalBasis)
+	f.city = strings.ToLower(opts.city)
+	f.postalCodePrefix = opts.postalCodePrefix
+	f.states = lowerAll(opts.states)
+	f.dedup = opts.dedup
+	f.includeDeleted = opts.includeDeleted
+
+	order, err := parseOrder(opts.sortField, opts.sortDesc)
+	if err != nil {
+		return nil, err
+	}
+	f.order = order
+	f.offset = opts.offset
+	f.limit = opts.limit
+
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+func (f *filter) validate() error {
+	if err := f.published.validate(); err != nil {
+		return fmt.Errorf("invalid published date range: %w", err)
+	}
+	if err := f.found.validate(); err != nil {
+		return fmt.Errorf("invalid found date range: %w", err)
+	}
+	return nil
+}
+
+func (f *filter) match(itm *item) bool {
+	return (f.authority == "" || strings.Contains(strings.ToLower(itm.Authority), f.authority)) &&
+		f.published.contains(itm.PublishedAt) &&
+		f.found.contains(itm.FoundAt) &&
+		(f.name == nil || f.name.MatchString(itm.Name)) &&
+		containsAny(strings.ToLower(itm.Reason), f.reasonKeywords) &&
+		(f.legalBasis == "" || strings.Contains(strings.ToLower(itm.LegalBasis), f.legalBasis)) &&
+		(f.city == "" || strings.Contains(strings.ToLower(itemCity(itm)), f.city)) &&
+		(f.postalCodePrefix == "" || strings.HasPrefix(itemPostalCode(itm), f.postalCodePrefix)) &&
+		(len(f.states) == 0 || slices.Contains(f.states, strings.ToLower(itm.State))) &&
+		(f.includeDeleted || itm.DeletedAt.IsZero())
+}
+
+// apply returns the items matching f. The items are filtered,
+// deduplicated if enabled, sorted, the offset is skipped and finally the
+// limit applied, so offset and limit count matching items only.
+func (f *filter) apply(items []*item) []*item {
+	matches := f.matching(items)
+	if f.dedup {
+		matches = dedupItems(matches)
+	}
+
+	f.order.sort(matches)
+
+	// An offset beyond the matches yields no items
+	matches = matches[min(max(f.offset, 0), len(matches)):]
+	if f.limit > 0 && len(matches) > f.limit {
+		matches = matches[:f.limit]
+	}
+
+	return matches
+}
+
+// matching returns the items matching f in their order, neither
+// deduplicated nor sorted, nor are offset and limit applied.
+func (f *filter) matching(items []*item) []*item {
+	matches := make([]*item, 0, len(items))
+	for _, itm := range items {
+		if f.match(itm) {
+			matches = append(matches, itm)
+		}
+	}
+	return matches
+}
+
+// dedupItems collapses the items with the same name, address and
+// reason, ignoring case and whitespace, as authorities sometimes reprint
+// an item under another date. The one published first is kept in place
+// of the first of them, items without a valid publication date are only
+// kept if there's no other.
+func dedupItems(items []*item) []*item {
+	var (
+		deduped = make([]*item, 0, len(items))
+		indices = make(map[string]int, len(items)) // Into deduped
+	)
+	for _, itm := range items {
+		key := normalizeText(itm.Name) + "\x00" + normalizeText(itm.Address) + "\x00" + normalizeText(itm.Reason)
+		i, ok := indices[key]
+		if !ok {
+			indices[key] = len(deduped)
+			deduped = append(deduped, itm)
+			continue
+		}
+		if kept := deduped[i]; !itm.PublishedAt.IsZero() &&
+			(kept.PublishedAt.IsZero() || itm.PublishedAt.Before(kept.PublishedAt)) {
+			deduped[i] = itm
+		}
+	}
+
+	return deduped
+}
+
+// normalizeText lowercases s and collapses its whitespace, so texts only
+// differing in case and whitespace compare equal.
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// postalCode extracts the postal code from address. It returns an
+// empty string if there is none.
+func postalCode(address string) string {
+	m := postalCodeRegexp.FindStringSubmatch(address)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// itemCity returns the city of itm, or its address if it couldn't be
+// parsed.
+func itemCity(itm *item) string {
+	if itm.City != "" {
+		return itm.City
+	}
+	return itm.Address
+}
+
+// itemPostalCode returns the postal code of itm, which may be found in
+// addresses which couldn't be parsed as well.
+func itemPostalCode(itm *item) string {
+	if itm.PostalCode != "" {
+		return itm.PostalCode
+	}
+	return postalCode(itm.Address)
+}
+
+// containsAny reports whether s contains any of substrs. It reports
+// true if there are no substrs.
+func containsAny(s string, substrs []string) bool {
+	if len(substrs) == 0 {
+		return true
+	}
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func lowerAll(ss []string) []string {
+	lower := make([]string, 0, len(ss))
+	for _, s := range ss {
+		lower = append(lower, strings.ToLower(s))
+	}
+	return lower
+}
+
+// sql returns the where, order by, limit and offset clauses selecting
+// the items matching f from the items table, and their arguments.
+func (f *filter) sql() (string, []any) {
+	where, args := f.sqlWhere()
+
+	var b strings.Builder
+	b.WriteString(where)
+	b.WriteString(" order by ")
+	b.WriteString(f.order.sql())
+
+	limit := -1 // No limit
+	if f.limit > 0 {
+		limit = f.limit
+	}
+	b.WriteString(" limit ? offset ?")
+	args = append(args, limit, max(f.offset, 0))
+
+	return b.String(), args
+}
+
+// sqlWhere returns the where clause matching the items of f, or an
+// empty string if all items match, and its arguments.
+func (f *filter) sqlWhere() (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	cond := func(c string, a ...any) {
+		conds = append(conds, c)
+		args = append(args, a...)
+	}
+
+	if f.authority != "" {
+		cond("instr("+sqliteFuncLower+"(authority), ?) > 0", f.authority)
+	}
+	for _, dr := range []struct {
+		col string
+		r   dateRange
+	}{
+		{"published_at", f.published},
+		{"found_at", f.found},
+	} {
+		col, r := dr.col, dr.r
+		if !r.isSet() {
+			continue
+		}
+		cond(col+" != ?", time.Time{}) // Dates which couldn't be parsed
+		if !r.after.IsZero() {
+			cond(col+" >= ?", r.after)
+		}
+		if !r.before.IsZero() {
+			cond(col+" <= ?", r.before)
+		}
+	}
+	if f.name != nil {
+		cond("name regexp ?", f.name.String())
+	}
+	if len(f.reasonKeywords) > 0 {
+		ors := make([]string, 0, len(f.reasonKeywords))
+		for _, kw := range f.reasonKeywords {
+			ors = append(ors, "instr("+sqliteFuncLower+"(reason), ?) > 0")
+			args = append(args, kw)
+		}
+		conds = append(conds, "("+strings.Join(ors, " or ")+")")
+	}
+	if f.legalBasis != "" {
+		cond("instr("+sqliteFuncLower+"(legal_basis), ?) > 0", f.legalBasis)
+	}
+	if f.city != "" {
+		cond("instr("+sqliteFuncLower+"(case when city != '' then city else address end), ?) > 0", f.city)
+	}
+	if f.postalCodePrefix != "" {
+		cond("instr(case when postal_code != '' then postal_code else "+sqliteFuncPostalCode+"(address) end, ?) = 1", f.postalCodePrefix)
+	}
+	if len(f.states) > 0 {
+		placeholders := make([]string, 0, len(f.states))
+		for _, s := range f.states {
+			placeholders = append(placeholders, "?")
+			args = append(args, s)
+		}
+		conds = append(conds, "state in ("+strings.Join(placeholders, ", ")+")")
+	}
+	if !f.includeDeleted {
+		cond("deleted_at is null")
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+
+	return " where " + strings.Join(conds, " and "), args
+}
+
+// daysAgo returns the date n days before the date of now. Like dates
+// parsed in timeFormat it is midnight in UTC.
+func daysAgo(now time.Time, n int) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -n)
+}
+
+// parseDate parses s in timeFormat. It returns the zero time if s is
+// empty.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(timeFormat, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse date %q, expected format %s: %w", s, timeFormat, err)
+	}
+
+	return t, nil
+}
+
+// compileFilterRegexp compiles pattern case-insensitively unless it
+// starts with its own flags. It returns nil if pattern is empty.
+func compileFilterRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil //nolint:nilnil // No pattern is not an error
+	}
+
+	if !regexpFlagsPrefix.MatchString(pattern) {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile regular expression %q: %w", pattern, err)
+	}
+
+	return re, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDateRangeContains(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		name string
+		r    dateRange
+		t    time.Time
+		want bool
+	}{
+		{"unset", dateRange{}, day(1), true},
+		{"unset zero", dateRange{}, time.Time{}, true},
+		{"before start", dateRange{after: day(10), before: day(20)}, day(9), false},
+		{"on start", dateRange{after: day(10), before: day(20)}, day(10), true},
+		{"within", dateRange{after: day(10), before: day(20)}, day(15), true},
+		{"on end", dateRange{after: day(10), before: day(20)}, day(20), true},
+		{"after end", dateRange{after: day(10), before: day(20)}, day(21), false},
+		{"single day", dateRange{after: day(10), before: day(10)}, day(10), true},
+		{"open start on end", dateRange{before: day(20)}, day(20), true},
+		{"open start after end", dateRange{before: day(20)}, day(21), false},
+		{"open end on start", dateRange{after: day(10)}, day(10), true},
+		{"open end before start", dateRange{after: day(10)}, day(9), false},
+		{"zero", dateRange{after: day(10), before: day(20)}, time.Time{}, false},
+	} {
+		if got := tc.r.contains(tc.t); got != tc.want {
+			t.Errorf("%s: contains(%s) = %t, want %t", tc.name, tc.t.Format(timeFormat), got, tc.want)
+		}
+	}
+}
+
+func TestFilterDateBoundaries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+
+	st, err := openSQLite(ctx, l, sqliteConfig{file: filepath.Join(t.TempDir(), "db.sqlite")})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer st.close(ctx, l)
+
+	// Named by their date, both published and found on it
+	var items []*item
+	for _, d := range []string{"09.03.2024", "10.03.2024", "15.03.2024", "20.03.2024", "21.03.2024", ""} {
+		date, err := parseDate(d)
+		if err != nil {
+			t.Fatalf("failed to parse date: %v", err)
+		}
+		items = append(items, &item{
+			State:       "bw",
+			Authority:   "Stadt Stuttgart",
+			PublishedAt: date,
+			FoundAt:     date,
+			Name:        "Item " + d,
+			Address:     "Königstraße 1, 70173 Stuttgart",
+		})
+	}
+	if _, err := st.storeItems(ctx, l, time.Now(), items); err != nil {
+		t.Fatalf("failed to store items: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts filterOptions
+		want []string
+	}{
+		{
+			"published range",
+			filterOptions{publishedAfter: "10.03.2024", publishedBefore: "20.03.2024"},
+			[]string{"Item 10.03.2024", "Item 15.03.2024", "Item 20.03.2024"},
+		},
+		{
+			"published single day",
+			filterOptions{publishedAfter: "10.03.2024", publishedBefore: "10.03.2024"},
+			[]string{"Item 10.03.2024"},
+		},
+		{
+			"published after",
+			filterOptions{publishedAfter: "20.03.2024"},
+			[]string{"Item 20.03.2024", "Item 21.03.2024"},
+		},
+		{
+			"published before",
+			filterOptions{publishedBefore: "10.03.2024"},
+			[]string{"Item 09.03.2024", "Item 10.03.2024"},
+		},
+		{
+			"found range",
+			filterOptions{foundAfter: "10.03.2024", foundBefore: "20.03.2024"},
+			[]string{"Item 10.03.2024", "Item 15.03.2024", "Item 20.03.2024"},
+		},
+		{
+			"found single day",
+			filterOptions{foundAfter: "20.03.2024", foundBefore: "20.03.2024"},
+			[]string{"Item 20.03.2024"},
+		},
+	} {
+		tc.opts.sortField = sortByPublished
+		f, err := newFilter(tc.opts, time.Now())
+		if err != nil {
+			t.Fatalf("%s: failed to create filter: %v", tc.name, err)
+		}
+
+		if got := itemNames(f.apply(items)); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %q filtering the items, want %q", tc.name, got, tc.want)
+		}
+
+		stored, err := st.queryItems(ctx, l, f)
+		if err != nil {
+			t.Fatalf("%s: failed to query items: %v", tc.name, err)
+		}
+		if got := itemNames(stored); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %q querying the database, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func itemNames(items []*item) []string {
+	names := make([]string, 0, len(items))
+	for _, itm := range items {
+		names = append(names, itm.Name)
+	}
+	return names
+}
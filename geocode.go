@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultGeocoderURL          = "https://nominatim.openstreetmap.org"
+	defaultGeocodeCacheFilePath = "./geocode-cache.json"
+	geocodeCacheFilePermissions = 0o600
+
+	// See https://operations.osmfoundation.org/policies/nominatim/
+	nominatimMinRequestInterval = time.Second
+
+	// Of the requests made at once, see GEOCODE_CONCURRENCY. It overlaps
+	// slow responses, the rate is still bounded by GEOCODE_INTERVAL.
+	defaultGeocodeConcurrency = 4
+)
+
+var errAddressNotFound = errors.New("address not found")
+
+type coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type geocoder interface {
+	// Geocode resolves address to coordinates. It returns
+	// errAddressNotFound if the address is unknown to the geocoder. It
+	// must be safe for concurrent use.
+	Geocode(ctx context.Context, address string) (*coordinates, error)
+}
+
+type nominatimGeocoder struct {
+	l         *slog.Logger
+	baseURL   string
+	userAgent string
+	client    *http.Client
+	// Honors the geocoder's rate limit, may be nil
+	limiter *fetchLimiter
+}
+
+func newNominatimGeocoder(
+	l *slog.Logger,
+	baseURL string,
+	userAgent string,
+	requestTimeout time.Duration,
+	interval time.Duration,
+) *nominatimGeocoder {
+	return &nominatimGeocoder{
+		l:         l,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		userAgent: userAgent,
+		client: &http.Client{
+			Timeout: requestTimeout,
+		},
+		limiter: newFetchLimiter(interval),
+	}
+}
+
+func (g *nominatimGeocoder) Geocode(ctx context.Context, address string) (*coordinates, error) {
+	if err := g.limiter.wait(ctx); err != nil {
+		return nil, fmt.Errorf("failed to wait for geocoder: %w", err)
+	}
+
+	q := url.Values{}
+	q.Set("q", address)
+	q.Set("format", "jsonv2")
+	q.Set("countrycodes", "de")
+	q.Set("limit", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/search?"+q.Encode(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create geocode request: %w", err)
+	}
+	// Required by the usage policy
+	req.Header.Set("User-Agent", g.userAgent)
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to geocode: %w", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			g.l.ErrorContext(ctx, fmt.Errorf("failed to close body: %w", err).Error())
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to geocode: unexpected status code %d", res.StatusCode)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode geocode response: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, errAddressNotFound
+	}
+
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse latitude %q: %w", results[0].Lat, err)
+	}
+	lon, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse longitude %q: %w", results[0].Lon, err)
+	}
+
+	return &coordinates{
+		Lat: lat,
+		Lon: lon,
+	}, nil
+}
+
+// geocoderConfig configures the geocoder used by GeoJSON output and
+// -geocode.
+type geocoderConfig struct {
+	url       string
+	cacheFile string
+	userAgent string
+	timeout   time.Duration
+	// Between requests, not limited if zero
+	interval time.Duration
+	// Maximum number of requests made at once
+	concurrency int
+}
+
+// openGeocoder returns a Nominatim geocoder using the cache file of cfg.
+// Its cache must be saved once done.
+func openGeocoder(l *slog.Logger, cfg geocoderConfig) (*cachingGeocoder, error) {
+	return newCachingGeocoder(
+		newNominatimGeocoder(l, cfg.url, cfg.userAgent, cfg.timeout, cfg.interval),
+		cfg.cacheFile,
+	)
+}
+
+// cachingGeocoder wraps a geocoder with a cache persisted to disk, keyed
+// by the normalized address, see normalizeText. Addresses unknown to the
+// geocoder are cached as well.
+type cachingGeocoder struct {
+	geocoder geocoder
+	path     string
+
+	mu    sync.Mutex
+	cache map[string]*coordinates
+	dirty bool
+}
+
+func newCachingGeocoder(g geocoder, path string) (*cachingGeocoder, error) {
+	cg := &cachingGeocoder{
+		geocoder: g,
+		path:     path,
+		cache:    make(map[string]*coordinates),
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read geocode cache: %w", err)
+	}
+	var cache map[string]*coordinates
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return nil, fmt.Errorf("failed to decode geocode cache: %w", err)
+	}
+	// Caches written before were keyed by the address as is
+	for address, c := range cache {
+		key := normalizeText(address)
+		if _, ok := cg.cache[key]; !ok || c != nil {
+			cg.cache[key] = c
+		}
+		cg.dirty = cg.dirty || key != address
+	}
+
+	return cg, nil
+}
+
+func (g *cachingGeocoder) Geocode(ctx context.Context, address string) (*coordinates, error) {
+	key := normalizeText(address)
+
+	g.mu.Lock()
+	c, ok := g.cache[key]
+	g.mu.Unlock()
+	if ok {
+		if c == nil {
+			return nil, errAddressNotFound
+		}
+		return c, nil
+	}
+
+	c, err := g.geocoder.Geocode(ctx, address)
+	if err != nil && !errors.Is(err, errAddressNotFound) {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	g.cache[key] = c
+	g.dirty = true
+	g.mu.Unlock()
+
+	return c, err
+}
+
+// Save atomically writes the cache to disk if it has changed.
+func (g *cachingGeocoder) Save() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.dirty {
+		return nil
+	}
+
+	if err := writeFileAtomic(g.path, geocodeCacheFilePermissions, func(w io.Writer) error {
+		if err := json.NewEncoder(w).Encode(g.cache); err != nil {
+			return fmt.Errorf("failed to encode geocode cache: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save geocode cache: %w", err)
+	}
+
+	g.dirty = false
+
+	return nil
+}
+
+// itemCoordinates returns the coordinates of itm, nil if it hasn't been
+// geocoded.
+func itemCoordinates(itm *item) *coordinates {
+	if itm.Latitude == 0 && itm.Longitude == 0 {
+		return nil
+	}
+	return &coordinates{
+		Lat: itm.Latitude,
+		Lon: itm.Longitude,
+	}
+}
+
+// geocodeItems resolves the address of each item which hasn't been
+// geocoded yet, making up to concurrency requests at once. Each address
+// is geocoded once only, ignoring case and whitespace. Addresses which
+// fail to geocode are logged and mapped to nil.
+func geocodeItems(
+	ctx context.Context,
+	l *slog.Logger,
+	g geocoder,
+	items []*item,
+	concurrency int,
+) []*coordinates {
+	coords := make([]*coordinates, len(items))
+
+	// Indices of the items by normalized address, in order of appearance
+	var (
+		addresses []string
+		indices   = make(map[string][]int)
+	)
+	for i, itm := range items {
+		if c := itemCoordinates(itm); c != nil {
+			coords[i] = c
+			continue
+		}
+		if itm.Address == "" {
+			continue
+		}
+
+		key := normalizeText(itm.Address)
+		if _, ok := indices[key]; !ok {
+			addresses = append(addresses, itm.Address)
+		}
+		indices[key] = append(indices[key], i)
+	}
+
+	var eg errgroup.Group
+	eg.SetLimit(max(concurrency, 1))
+	for _, address := range addresses {
+		eg.Go(func() error {
+			// Would fail anyway, don't log each of them
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			c, err := g.Geocode(ctx, address)
+			if err != nil {
+				l.WarnContext(
+					ctx,
+					"failed to geocode address",
+					"address", address,
+					"err", err,
+				)
+				return nil
+			}
+			// The items of an address aren't set by any other
+			for _, i := range indices[normalizeText(address)] {
+				coords[i] = c
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait() // Failures are logged only
+
+	return coords
+}
+
+// setItemCoordinates geocodes the items which haven't been geocoded yet
+// and sets their coordinates.
+func setItemCoordinates(ctx context.Context, l *slog.Logger, cfg geocoderConfig, items []*item) error {
+	g, err := openGeocoder(l, cfg)
+	if err != nil {
+		return err
+	}
+
+	for i, c := range geocodeItems(ctx, l, g, items, cfg.concurrency) {
+		if c != nil {
+			items[i].Latitude = c.Lat
+			items[i].Longitude = c.Lon
+		}
+	}
+
+	if err := g.Save(); err != nil {
+		// Not fatal, we'll just have to geocode again next time
+		l.WarnContext(ctx, err.Error())
+	}
+
+	// Geocoding stops failing once ctx is done
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to geocode items: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqllog "github.com/graph-gophers/graphql-go/log"
+)
+
+const (
+	// Bounds the GraphQL requests read
+	graphQLMaxRequestSize = 1 << 20
+	// Of the fields of queries, the schema is shallow
+	graphQLMaxDepth = 5
+)
+
+// graphQLSchema describes the items served at /graphql. Dates are
+// formatted like the filter flags, DD.MM.YYYY.
+const graphQLSchema = `
+scalar Time
+
+type Query {
+	# The stored items matching all arguments, latest published last.
+	# search matches the items containing all of its words in their name,
+	# address, reason or info, like the search subcommand. state matches
+	# the items of any of the Bundesländer, e.g. bw. Soft-deleted items
+	# only match if includeDeleted is set.
+	items(
+		authority: String
+		publishedAfter: String
+		publishedBefore: String
+		state: [String!]
+		includeDeleted: Boolean
+		search: String
+		offset: Int
+		limit: Int
+	): [Item!]!
+}
+
+# A published result of a food inspection. Unknown dates are null.
+type Item {
+	authority: String!
+	publishedAt: Time
+	foundAt: Time
+	publishedAtEnd: Time
+	foundAtEnd: Time
+	name: String!
+	address: String!
+	reason: String!
+	legalBasis: String!
+	info: String!
+	street: String!
+	postalCode: String!
+	city: String!
+	state: String!
+	latitude: Float!
+	longitude: Float!
+	firstSeen: Time
+	lastSeen: Time
+	vanished: Boolean!
+	mapUrl: String!
+	authorityNormalized: String!
+	deletedAt: Time
+}
+`
+
+// newGraphQLHandler returns the handler of GraphQL queries of the
+// stored items, posted as JSON objects with a query, operationName and
+// variables.
+func newGraphQLHandler(l *slog.Logger, st storage) (http.Handler, error) {
+	schema, err := graphql.ParseSchema(
+		graphQLSchema,
+		&graphQLResolver{l: l, st: st},
+		graphql.MaxDepth(graphQLMaxDepth),
+		graphql.Logger(gqllog.LoggerFunc(func(ctx context.Context, v any) {
+			l.ErrorContext(ctx, "GraphQL resolver panicked",
+				"panic", fmt.Sprint(v),
+			)
+		})),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GraphQL schema: %w", err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var params struct {
+			Query         string         `json:"query"`
+			OperationName string         `json:"operationName"`
+			Variables     map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, graphQLMaxRequestSize)).Decode(&params); err != nil {
+			writeJSONError(ctx, l, w, http.StatusBadRequest, errors.New("invalid GraphQL request"))
+			return
+		}
+
+		res := schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to write response: %w", err).Error())
+		}
+	}), nil
+}
+
+type graphQLResolver struct {
+	l  *slog.Logger
+	st storage
+}
+
+type graphQLItemsArgs struct {
+	Authority       *string
+	PublishedAfter  *string
+	PublishedBefore *string
+	State           *[]string
+	IncludeDeleted  *bool
+	Search          *string
+	Offset          *int32
+	Limit           *int32
+}
+
+// Items resolves the items query using the filter, searching the items
+// first if requested.
+func (r *graphQLResolver) Items(ctx context.Context, args graphQLItemsArgs) ([]*graphQLItem, error) {
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	opts := filterOptions{
+		authority:       deref(args.Authority),
+		publishedAfter:  deref(args.PublishedAfter),
+		publishedBefore: deref(args.PublishedBefore),
+		sortField:       sortByPublished,
+	}
+	if args.State != nil {
+		opts.states = *args.State
+	}
+	if args.IncludeDeleted != nil {
+		opts.includeDeleted = *args.IncludeDeleted
+	}
+	if args.Offset != nil {
+		opts.offset = int(*args.Offset)
+	}
+	if args.Limit != nil {
+		opts.limit = int(*args.Limit)
+	}
+	f, err := newFilter(opts, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*item
+	if terms := strings.Fields(deref(args.Search)); len(terms) > 0 {
+		if items, err = r.st.searchItems(ctx, r.l, terms); err == nil {
+			items = f.apply(items)
+		}
+	} else {
+		items, err = r.st.queryItems(ctx, r.l, f)
+	}
+	if err != nil {
+		r.l.ErrorContext(ctx, err.Error())
+		return nil, errors.New("failed to query items")
+	}
+
+	resolved := make([]*graphQLItem, 0, len(items))
+	for _, itm := range items {
+		resolved = append(resolved, &graphQLItem{itm})
+	}
+	return resolved, nil
+}
+
+// graphQLItem resolves the fields of an item.
+type graphQLItem struct {
+	itm *item
+}
+
+// graphQLTime returns t as a GraphQL time, nil if unknown.
+func graphQLTime(t time.Time) *graphql.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: t}
+}
+
+func (i *graphQLItem) Authority() string             { return i.itm.Authority }
+func (i *graphQLItem) PublishedAt() *graphql.Time    { return graphQLTime(i.itm.PublishedAt) }
+func (i *graphQLItem) FoundAt() *graphql.Time        { return graphQLTime(i.itm.FoundAt) }
+func (i *graphQLItem) PublishedAtEnd() *graphql.Time { return graphQLTime(i.itm.PublishedAtEnd) }
+func (i *graphQLItem) FoundAtEnd() *graphql.Time     { return graphQLTime(i.itm.FoundAtEnd) }
+func (i *graphQLItem) Name() string                  { return i.itm.Name }
+func (i *graphQLItem) Address() string               { return i.itm.Address }
+func (i *graphQLItem) Reason() string                { return i.itm.Reason }
+func (i *graphQLItem) LegalBasis() string            { return i.itm.LegalBasis }
+func (i *graphQLItem) Info() string                  { return i.itm.Info }
+func (i *graphQLItem) Street() string                { return i.itm.Street }
+func (i *graphQLItem) PostalCode() string            { return i.itm.PostalCode }
+func (i *graphQLItem) City() string                  { return i.itm.City }
+func (i *graphQLItem) State() string                 { return i.itm.State }
+func (i *graphQLItem) Latitude() float64             { return i.itm.Latitude }
+func (i *graphQLItem) Longitude() float64            { return i.itm.Longitude }
+func (i *graphQLItem) FirstSeen() *graphql.Time      { return graphQLTime(i.itm.FirstSeen) }
+func (i *graphQLItem) LastSeen() *graphql.Time       { return graphQLTime(i.itm.LastSeen) }
+func (i *graphQLItem) Vanished() bool                { return i.itm.Vanished }
+func (i *graphQLItem) MapURL() string                { return i.itm.MapURL }
+func (i *graphQLItem) AuthorityNormalized() string   { return i.itm.AuthorityNormalized }
+func (i *graphQLItem) DeletedAt() *graphql.Time      { return graphQLTime(i.itm.DeletedAt) }
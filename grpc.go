@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/leonklingele/lmk/lmkpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Of the new items of a stream not sent yet, in scrapes
+const itemStreamBuffer = 16
+
+// runGRPC serves the stored items via gRPC at addr until ctx is done, see
+// lmkpb/lmk.proto. If scrape isn't nil, it's run until ctx is done as
+// well, pushing the new items to streams. Running calls are given
+// serveShutdownTimeout to complete.
+func runGRPC(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	addr string,
+	streams *itemStreams,
+	scrape func(ctx context.Context) error,
+) error {
+	open := openExistingStorage
+	if scrape != nil {
+		// The scrapes create the database if missing anyway
+		open = openStorage
+	}
+	st, err := open(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	ln, err := listen(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer()
+	lmkpb.RegisterItemsServer(srv, &itemsServer{
+		l:       l,
+		st:      st,
+		streams: streams,
+	})
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+	l.InfoContext(ctx, "serving gRPC",
+		"addr", ln.Addr().String(),
+	)
+
+	scrapeCtx, cancelScrape := context.WithCancel(ctx)
+	defer cancelScrape()
+	scrapec := make(chan error, 1)
+	if scrape != nil {
+		go func() {
+			scrapec <- scrape(scrapeCtx)
+		}()
+	} else {
+		scrapec <- nil
+	}
+
+	select {
+	case err := <-errc:
+		cancelScrape()
+		<-scrapec
+		return fmt.Errorf("failed to serve gRPC: %w", err)
+	case <-ctx.Done():
+	}
+
+	l.InfoContext(ctx, "shutting down gRPC server")
+	if err := <-scrapec; err != nil {
+		l.ErrorContext(ctx, err.Error())
+	}
+	// Ends the streams, they're waited for otherwise
+	if streams != nil {
+		streams.close()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	t := time.NewTimer(serveShutdownTimeout)
+	defer t.Stop()
+	select {
+	case <-stopped:
+	case <-t.C:
+		srv.Stop()
+		return errors.New("failed to shut down gRPC server gracefully, timed out")
+	}
+	if err := <-errc; err != nil {
+		return fmt.Errorf("failed to serve gRPC: %w", err)
+	}
+	l.InfoContext(ctx, "stopped serving gRPC",
+		"addr", ln.Addr().String(),
+	)
+
+	return nil
+}
+
+type itemsServer struct {
+	lmkpb.UnimplementedItemsServer
+
+	l  *slog.Logger
+	st storage
+	// Nil if not scraping
+	streams *itemStreams
+}
+
+func (s *itemsServer) ListItems(ctx context.Context, req *lmkpb.ListItemsRequest) (*lmkpb.ListItemsResponse, error) {
+	opts := filterOptionsFromProto(req.GetFilter())
+	opts.sortField = req.GetSort()
+	if opts.sortField == "" {
+		opts.sortField = sortByPublished
+	}
+	opts.sortDesc = req.GetDesc()
+	opts.offset = int(req.GetOffset())
+	opts.limit = int(req.GetLimit())
+
+	f, err := newFilter(opts, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	items, err := s.st.queryItems(ctx, s.l, f)
+	if err != nil {
+		s.l.ErrorContext(ctx, err.Error())
+		return nil, status.Error(codes.Internal, "failed to query items")
+	}
+
+	res := &lmkpb.ListItemsResponse{
+		Items: make([]*lmkpb.Item, 0, len(items)),
+	}
+	for _, itm := range items {
+		res.Items = append(res.Items, itemToProto(itm))
+	}
+	return res, nil
+}
+
+func (s *itemsServer) StreamNewItems(
+	req *lmkpb.StreamNewItemsRequest,
+	stream grpc.ServerStreamingServer[lmkpb.Item],
+) error {
+	if s.streams == nil {
+		return status.Error(codes.FailedPrecondition, "not scraping, see the -watch flag of the server")
+	}
+
+	opts := filterOptionsFromProto(req.GetFilter())
+	opts.sortField = sortByPublished
+	f, err := newFilter(opts, time.Now())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx := stream.Context()
+	newItems, unsubscribe := s.streams.subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case items, ok := <-newItems:
+			if !ok {
+				// Shutting down
+				return nil
+			}
+			for _, itm := range f.apply(items) {
+				if err := stream.Send(itemToProto(itm)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// filterOptionsFromProto reads the filter options from pf, which may be
+// nil, see filterOptionsFromQuery.
+func filterOptionsFromProto(pf *lmkpb.Filter) filterOptions {
+	return filterOptions{
+		publishedAfter:   pf.GetPublishedAfter(),
+		publishedBefore:  pf.GetPublishedBefore(),
+		sinceDays:        int(pf.GetSinceDays()),
+		foundAfter:       pf.GetFoundAfter(),
+		foundBefore:      pf.GetFoundBefore(),
+		authority:        pf.GetAuthority(),
+		reasonKeywords:   pf.GetReason(),
+		legalBasis:       pf.GetLegalBasis(),
+		city:             pf.GetCity(),
+		postalCodePrefix: pf.GetPostalCodePrefix(),
+		states:           pf.GetState(),
+		nameRegex:        pf.GetNameRegex(),
+		dedup:            pf.GetDedup(),
+		includeDeleted:   pf.GetIncludeDeleted(),
+	}
+}
+
+func itemToProto(itm *item) *lmkpb.Item {
+	// Leaves unknown dates unset
+	timestamp := func(t time.Time) *timestamppb.Timestamp {
+		if t.IsZero() {
+			return nil
+		}
+		return timestamppb.New(t)
+	}
+
+	return &lmkpb.Item{
+		Authority:           itm.Authority,
+		PublishedAt:         timestamp(itm.PublishedAt),
+		FoundAt:             timestamp(itm.FoundAt),
+		PublishedAtEnd:      timestamp(itm.PublishedAtEnd),
+		FoundAtEnd:          timestamp(itm.FoundAtEnd),
+		Name:                itm.Name,
+		Address:             itm.Address,
+		Reason:              itm.Reason,
+		LegalBasis:          itm.LegalBasis,
+		Info:                itm.Info,
+		Street:              itm.Street,
+		PostalCode:          itm.PostalCode,
+		City:                itm.City,
+		State:               itm.State,
+		Latitude:            itm.Latitude,
+		Longitude:           itm.Longitude,
+		FirstSeen:           timestamp(itm.FirstSeen),
+		LastSeen:            timestamp(itm.LastSeen),
+		Vanished:            itm.Vanished,
+		MapUrl:              itm.MapURL,
+		AuthorityNormalized: itm.AuthorityNormalized,
+		DeletedAt:           timestamp(itm.DeletedAt),
+	}
+}
+
+// itemStreams passes the new items of the scrapes on to the streams of
+// StreamNewItems. It's notified like the other notifiers.
+type itemStreams struct {
+	mu      sync.Mutex
+	streams map[chan []*item]struct{}
+	closed  bool
+}
+
+func newItemStreams() *itemStreams {
+	return &itemStreams{
+		streams: make(map[chan []*item]struct{}),
+	}
+}
+
+func (s *itemStreams) Name() string {
+	return "gRPC"
+}
+
+// Notify passes the items on to all streams. Streams which fell behind
+// miss them, blocking would block the scrapes.
+func (s *itemStreams) Notify(_ context.Context, items []*item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var dropped int
+	for c := range s.streams {
+		select {
+		case c <- items:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("failed to stream new items to %d of %d clients, too slow", dropped, len(s.streams))
+	}
+
+	return nil
+}
+
+// subscribe returns a channel receiving the new items of each scrape,
+// which is closed once s is. unsubscribe must be called once done.
+func (s *itemStreams) subscribe() (<-chan []*item, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := make(chan []*item, itemStreamBuffer)
+	if s.closed {
+		close(c)
+		return c, func() {}
+	}
+	s.streams[c] = struct{}{}
+
+	return c, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.streams, c)
+	}
+}
+
+// close closes the channels of all streams, ending them.
+func (s *itemStreams) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for c := range s.streams {
+		close(c)
+		delete(s.streams, c)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// The statements are understood by both sqlite and Postgres.
+const (
+	insertHistoryStmt = `
+		insert into history (state, name, address, field, old_value, new_value, changed_at)
+		values ($1, $2, $3, $4, $5, $6, $7);
+	`
+	// Ordered by the instant of changed_at, see sqlStorage.timeExpr
+	selectHistoryStmt = `
+		select state, name, address, field, old_value, new_value, changed_at from history
+		where state = $1 and name = $2 and address = $3
+		order by %s, id;
+	`
+	countHistoryStmt = `
+		select count(*) from history
+	`
+
+	// Of the history printed by query and search, see -history
+	labelHistoryField    = "Feld"
+	labelHistoryOldValue = "Vorher"
+	labelHistoryNewValue = "Nachher"
+	labelHistoryChanged  = "Geändert"
+)
+
+// historyEntry is the change of a field of a business's item, i.e. of an
+// item with the same state, name and address, which was published again
+// with different content, e.g. an amended reason.
+type historyEntry struct {
+	State    string    `json:"state"`
+	Name     string    `json:"name"`
+	Address  string    `json:"address"`
+	Field    string    `json:"field"` // Column name, e.g. reason
+	OldValue string    `json:"old_value"`
+	NewValue string    `json:"new_value"`
+	Changed  time.Time `json:"changed_at"`
+}
+
+// historyColumns returns the columns compared by itemChanges, the
+// scraped ones not identifying the business.
+func historyColumns() []column {
+	var columns []column
+	for _, c := range itemColumns() {
+		switch c.name {
+		case columnAuthority,
+			columnPublishedAt,
+			columnFoundAt,
+			columnReason,
+			columnLegalBasis,
+			columnInfo,
+			columnPublishedAtEnd,
+			columnFoundAtEnd:
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// itemChanges returns the fields changed from old to itm, which are of
+// the same business, as of changedAt.
+func itemChanges(old, itm *item, changedAt time.Time) []historyEntry {
+	var changes []historyEntry
+	for _, c := range historyColumns() {
+		before, after := c.text(old), c.text(itm)
+		if before == after {
+			continue
+		}
+		changes = append(changes, historyEntry{
+			State:    itm.State,
+			Name:     itm.Name,
+			Address:  itm.Address,
+			Field:    c.name,
+			OldValue: before,
+			NewValue: after,
+			Changed:  changedAt,
+		})
+	}
+	return changes
+}
+
+func (s *sqlStorage) recordHistory(
+	ctx context.Context,
+	l *slog.Logger,
+	changedAt time.Time,
+	items []*item,
+) (int, error) {
+	var changes []historyEntry
+	for _, itm := range items {
+		// The latest other item of the business, if any
+		var old *item
+		if err := s.selectItems(ctx, l, func(o *item) error {
+			old = o
+			return nil
+		}, " where state = $1 and name = $2 and address = $3 and hash != $4 order by id desc limit 1",
+			itm.State, itm.Name, itm.Address, itemHash(itm),
+		); err != nil {
+			return 0, err
+		}
+		if old != nil {
+			changes = append(changes, itemChanges(old, itm, changedAt)...)
+		}
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin history transaction: %w", err)
+	}
+	for _, c := range changes {
+		if _, err := tx.ExecContext(
+			ctx,
+			insertHistoryStmt,
+			c.State,
+			c.Name,
+			c.Address,
+			c.Field,
+			c.OldValue,
+			c.NewValue,
+			c.Changed,
+		); err != nil {
+			return 0, errors.Join(fmt.Errorf("failed to insert history: %w", err), tx.Rollback())
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit history transaction: %w", err)
+	}
+
+	l.InfoContext(ctx, "recorded changes of items", "changes", len(changes))
+
+	return len(changes), nil
+}
+
+func (s *sqlStorage) itemHistory(ctx context.Context, l *slog.Logger, items []*item) ([]historyEntry, error) {
+	type business struct{ state, name, address string }
+
+	var (
+		entries []historyEntry
+		seen    = make(map[business]bool)
+	)
+	for _, itm := range items {
+		b := business{itm.State, itm.Name, itm.Address}
+		if seen[b] {
+			continue
+		}
+		seen[b] = true
+
+		bEntries, err := s.queryHistory(ctx, l, b.state, b.name, b.address)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, bEntries...)
+	}
+
+	return entries, nil
+}
+
+func (s *sqlStorage) queryHistory(
+	ctx context.Context,
+	l *slog.Logger,
+	state, name, address string,
+) ([]historyEntry, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(selectHistoryStmt, fmt.Sprintf(s.timeExpr, "changed_at")), state, name, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close rows: %w", err).Error())
+		}
+	}()
+
+	var entries []historyEntry
+	for rows.Next() {
+		var (
+			e       historyEntry
+			changed dbTime
+		)
+		if err := rows.Scan(
+			&e.State,
+			&e.Name,
+			&e.Address,
+			&e.Field,
+			&e.OldValue,
+			&e.NewValue,
+			&changed,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan history: %w", err)
+		}
+		e.Changed = changed.Time
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// renderHistory prints the history entries as a table, Markdown, CSV or
+// JSON.
+func renderHistory(w io.Writer, entries []historyEntry, out outputOptions) error {
+	header := []string{labelName, labelAddress, labelHistoryField, labelHistoryOldValue, labelHistoryNewValue, labelHistoryChanged}
+	records := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		records = append(records, []string{e.Name, e.Address, e.Field, e.OldValue, e.NewValue, formatTimestamp(e.Changed)})
+	}
+
+	switch out.format {
+	case outputFormatTable, outputFormatMarkdown:
+		t := table.NewWriter()
+		t.SetTitle("Änderungen")
+		t.AppendHeader(stringsRow(header))
+		for _, r := range records {
+			if out.format == outputFormatTable && out.tableMaxWidth > 0 {
+				for i := range r {
+					r[i] = capstring(r[i], out.tableMaxWidth)
+				}
+			}
+			t.AppendRow(stringsRow(r))
+		}
+		s := t.Render()
+		if out.format == outputFormatMarkdown {
+			s = t.RenderMarkdown()
+		}
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return fmt.Errorf("failed to print history: %w", err)
+		}
+		return nil
+	case outputFormatCSV:
+		cw := csv.NewWriter(w)
+		cw.UseCRLF = true
+		if err := cw.WriteAll(append([][]string{header}, records...)); err != nil {
+			return fmt.Errorf("failed to CSV-print history: %w", err)
+		}
+		return nil
+	case outputFormatJSON:
+		enc := newJSONEncoder(w, out.jsonIndent)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return fmt.Errorf("failed to JSON-print history: %w", err)
+			}
+		}
+		return nil
+	case outputFormatJSONArray:
+		if entries == nil {
+			entries = []historyEntry{} // Print an empty array instead of null
+		}
+		if err := newJSONEncoder(w, out.jsonIndent).Encode(entries); err != nil {
+			return fmt.Errorf("failed to JSON-print history: %w", err)
+		}
+		return nil
+	}
+
+	return errors.New("history can be printed as a table, Markdown, CSV or JSON only")
+}
+
+func stringsRow(ss []string) table.Row {
+	row := make(table.Row, 0, len(ss))
+	for _, s := range ss {
+		row = append(row, s)
+	}
+	return row
+}
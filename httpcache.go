@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+const (
+	httpCacheFileName        = "http-cache.json"
+	httpCacheFilePermissions = 0o600
+)
+
+var errPageNotModified = errors.New("page not modified")
+
+// pageValidators identify the version of the page fetched last, so the
+// server can tell whether it changed since.
+type pageValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func validatorsFromResponse(res *http.Response) pageValidators {
+	return pageValidators{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+}
+
+// setConditional makes req conditional on the page having changed.
+func (v pageValidators) setConditional(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// loadPageValidators reads the validators saved by savePageValidators.
+// They are empty if the file doesn't exist yet.
+func loadPageValidators(path string) (pageValidators, error) {
+	var v pageValidators
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return v, fmt.Errorf("failed to read HTTP cache: %w", err)
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, fmt.Errorf("failed to decode HTTP cache: %w", err)
+	}
+
+	return v, nil
+}
+
+func savePageValidators(path string, v pageValidators) error {
+	if err := writeFileAtomic(path, httpCacheFilePermissions, func(w io.Writer) error {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			return fmt.Errorf("failed to encode HTTP cache: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save HTTP cache: %w", err)
+	}
+
+	return nil
+}
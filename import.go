@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// decodeItems decodes the items of a JSON array, or of newline-delimited
+// JSON as printed by export, and validates them.
+func decodeItems(r io.Reader) ([]*item, error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	dec.DisallowUnknownFields()
+
+	isArray, err := startsWithArray(br)
+	if err != nil {
+		return nil, err
+	}
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to JSON-decode items: %w", err)
+		}
+	}
+
+	var items []*item
+	for dec.More() {
+		var itm item
+		if err := dec.Decode(&itm); err != nil {
+			return nil, fmt.Errorf("failed to JSON-decode item %d: %w", len(items)+1, err)
+		}
+		if err := validateItem(&itm); err != nil {
+			return nil, fmt.Errorf("invalid item %d: %w", len(items)+1, err)
+		}
+
+		// Not part of the JSON representation but of the item hash
+		itm.PublishedAtStr = formatDate(itm.PublishedAt)
+		itm.FoundAtStr = formatDate(itm.FoundAt)
+		if itm.Street == "" && itm.PostalCode == "" && itm.City == "" {
+			// Exported before addresses were parsed
+			setAddressParts(&itm)
+		}
+		// Derived rather than imported, e.g. if the map changed since
+		itm.MapURL = mapURL(itm.Address)
+		if itm.AuthorityNormalized == "" {
+			// Exported before authorities were normalized
+			itm.AuthorityNormalized = itm.Authority
+		}
+		if itm.State == "" {
+			// Exported before other sources were supported
+			itm.State = defaultSourceState
+		}
+
+		items = append(items, &itm)
+	}
+
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to JSON-decode items: %w", err)
+		}
+	}
+
+	return items, nil
+}
+
+// startsWithArray reports whether the next non-whitespace character is
+// the start of a JSON array.
+func startsWithArray(br *bufio.Reader) (bool, error) {
+	for {
+		b, err := br.Peek(1)
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("failed to read items: %w", err)
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := br.Discard(1); err != nil {
+				return false, fmt.Errorf("failed to read items: %w", err)
+			}
+		case '[':
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+}
+
+func validateItem(itm *item) error {
+	for _, f := range []struct {
+		name  string
+		value string
+	}{
+		{columnAuthority, itm.Authority},
+		{columnName, itm.Name},
+		{columnAddress, itm.Address},
+		{columnReason, itm.Reason},
+	} {
+		if f.value == "" {
+			return fmt.Errorf("missing %s", f.name)
+		}
+	}
+
+	return nil
+}
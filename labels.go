@@ -0,0 +1,83 @@
+package main
+
+// The labels used in the output, in German. Those of the scraped fields
+// must match the heading of the scraped table, see source.
+const (
+	labelAuthority   = "Behörde"
+	labelPublishedAt = "Datum Veröffentlichung"
+	labelFoundAt     = "Feststellungstag"
+	labelName        = "Betriebsbezeichnung"
+	labelAddress     = "Anschrift"
+	labelReason      = "Sachverhalt/Grund der Beanstandung"
+	labelLegalBasis  = "Rechtsgrundlage"
+	labelInfo        = "Hinweise zur Mängelbeseitigung und Bemerkungen"
+
+	// Not part of the scraped table heading
+	labelPublishedAtEnd = "Datum Veröffentlichung (bis)"
+	labelFoundAtEnd     = "Feststellungstag (bis)"
+	labelStreet         = "Straße"
+	labelPostalCode     = "PLZ"
+	labelCity           = "Ort"
+	labelState          = "Bundesland"
+	labelLatitude       = "Breitengrad"
+	labelLongitude      = "Längengrad"
+	labelMapURL         = "Karte"
+	labelFirstSeen      = "Erstmals gesehen"
+	labelLastSeen       = "Zuletzt gesehen"
+	labelVanished       = "Nicht mehr veröffentlicht"
+
+	labelAuthorityNormalized = "Behörde (vereinheitlicht)"
+	labelDeletedAt           = "Gelöscht"
+
+	// Of the stats subcommand
+	labelCount = "Anzahl"
+	labelMonth = "Monat der Veröffentlichung"
+
+	// Of the sections of -diff, besides labelVanished
+	labelDiffNew        = "Neu"
+	labelDiffReappeared = "Wieder veröffentlicht"
+)
+
+// englishColumnLabels returns the English labels of the columns by their
+// name, see -en.
+func englishColumnLabels() map[string]string {
+	return map[string]string{
+		columnAuthority:      "Authority",
+		columnPublishedAt:    "Published",
+		columnFoundAt:        "Inspection date",
+		columnName:           "Business",
+		columnAddress:        "Address",
+		columnReason:         "Reason",
+		columnLegalBasis:     "Legal basis",
+		columnInfo:           "Notes",
+		columnPublishedAtEnd: "Published (until)",
+		columnFoundAtEnd:     "Inspection date (until)",
+		columnStreet:         "Street",
+		columnPostalCode:     "Postal code",
+		columnCity:           "City",
+		columnState:          "State",
+		columnLatitude:       "Latitude",
+		columnLongitude:      "Longitude",
+		columnMapURL:         "Map",
+		columnFirstSeen:      "First seen",
+		columnLastSeen:       "Last seen",
+		columnVanished:       "Vanished",
+
+		columnAuthorityNormalized: "Authority (normalized)",
+		columnDeletedAt:           "Deleted",
+	}
+}
+
+// englishColumns returns copies of the columns labeled in English.
+func englishColumns(columns []column) []column {
+	labels := englishColumnLabels()
+
+	english := make([]column, 0, len(columns))
+	for _, c := range columns {
+		if label, ok := labels[c.name]; ok {
+			c.label = label
+		}
+		english = append(english, c)
+	}
+	return english
+}
@@ -0,0 +1,5 @@
+// Package lmkpb implements the gRPC service of the grpc subcommand, see
+// lmk.proto.
+package lmkpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative lmk.proto
@@ -0,0 +1,701 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: lmk.proto
+
+package lmkpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A published result of a food inspection. Unknown dates are unset.
+type Item struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Authority   string                 `protobuf:"bytes,1,opt,name=authority,proto3" json:"authority,omitempty"`
+	PublishedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=published_at,json=publishedAt,proto3" json:"published_at,omitempty"`
+	FoundAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=found_at,json=foundAt,proto3" json:"found_at,omitempty"`
+	// Ends of date ranges, unset for a single date
+	PublishedAtEnd *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=published_at_end,json=publishedAtEnd,proto3" json:"published_at_end,omitempty"`
+	FoundAtEnd     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=found_at_end,json=foundAtEnd,proto3" json:"found_at_end,omitempty"`
+	Name           string                 `protobuf:"bytes,6,opt,name=name,proto3" json:"name,omitempty"`
+	Address        string                 `protobuf:"bytes,7,opt,name=address,proto3" json:"address,omitempty"`
+	Reason         string                 `protobuf:"bytes,8,opt,name=reason,proto3" json:"reason,omitempty"`
+	LegalBasis     string                 `protobuf:"bytes,9,opt,name=legal_basis,json=legalBasis,proto3" json:"legal_basis,omitempty"`
+	Info           string                 `protobuf:"bytes,10,opt,name=info,proto3" json:"info,omitempty"`
+	// Parsed from the address, all empty if it couldn't be parsed
+	Street     string `protobuf:"bytes,11,opt,name=street,proto3" json:"street,omitempty"`
+	PostalCode string `protobuf:"bytes,12,opt,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	City       string `protobuf:"bytes,13,opt,name=city,proto3" json:"city,omitempty"`
+	// Bundesland of the source the item was scraped from
+	State string `protobuf:"bytes,14,opt,name=state,proto3" json:"state,omitempty"`
+	// Of the address, both zero if not geocoded
+	Latitude  float64 `protobuf:"fixed64,15,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude float64 `protobuf:"fixed64,16,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	// When the item was first stored in the database
+	FirstSeen *timestamppb.Timestamp `protobuf:"bytes,17,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"`
+	// When the item was last scraped
+	LastSeen *timestamppb.Timestamp `protobuf:"bytes,18,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	// Whether the item is stored but no longer published
+	Vanished bool `protobuf:"varint,19,opt,name=vanished,proto3" json:"vanished,omitempty"`
+	// Searches the address on a map, empty if there's no address
+	MapUrl string `protobuf:"bytes,20,opt,name=map_url,json=mapUrl,proto3" json:"map_url,omitempty"`
+	// Canonical name of the authority, the authority itself if it has none
+	AuthorityNormalized string `protobuf:"bytes,21,opt,name=authority_normalized,json=authorityNormalized,proto3" json:"authority_normalized,omitempty"`
+	// When the item was soft-deleted, unset unless it was
+	DeletedAt     *timestamppb.Timestamp `protobuf:"bytes,22,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Item) Reset() {
+	*x = Item{}
+	mi := &file_lmk_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Item) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Item) ProtoMessage() {}
+
+func (x *Item) ProtoReflect() protoreflect.Message {
+	mi := &file_lmk_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Item.ProtoReflect.Descriptor instead.
+func (*Item) Descriptor() ([]byte, []int) {
+	return file_lmk_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Item) GetAuthority() string {
+	if x != nil {
+		return x.Authority
+	}
+	return ""
+}
+
+func (x *Item) GetPublishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishedAt
+	}
+	return nil
+}
+
+func (x *Item) GetFoundAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FoundAt
+	}
+	return nil
+}
+
+func (x *Item) GetPublishedAtEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishedAtEnd
+	}
+	return nil
+}
+
+func (x *Item) GetFoundAtEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FoundAtEnd
+	}
+	return nil
+}
+
+func (x *Item) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Item) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Item) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Item) GetLegalBasis() string {
+	if x != nil {
+		return x.LegalBasis
+	}
+	return ""
+}
+
+func (x *Item) GetInfo() string {
+	if x != nil {
+		return x.Info
+	}
+	return ""
+}
+
+func (x *Item) GetStreet() string {
+	if x != nil {
+		return x.Street
+	}
+	return ""
+}
+
+func (x *Item) GetPostalCode() string {
+	if x != nil {
+		return x.PostalCode
+	}
+	return ""
+}
+
+func (x *Item) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Item) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Item) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Item) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Item) GetFirstSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return nil
+}
+
+func (x *Item) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *Item) GetVanished() bool {
+	if x != nil {
+		return x.Vanished
+	}
+	return false
+}
+
+func (x *Item) GetMapUrl() string {
+	if x != nil {
+		return x.MapUrl
+	}
+	return ""
+}
+
+func (x *Item) GetAuthorityNormalized() string {
+	if x != nil {
+		return x.AuthorityNormalized
+	}
+	return ""
+}
+
+func (x *Item) GetDeletedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return nil
+}
+
+// Selects items like the filter flags of the same names, all of which
+// must match.
+type Filter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Dates are formatted DD.MM.YYYY
+	PublishedAfter  string `protobuf:"bytes,1,opt,name=published_after,json=publishedAfter,proto3" json:"published_after,omitempty"`
+	PublishedBefore string `protobuf:"bytes,2,opt,name=published_before,json=publishedBefore,proto3" json:"published_before,omitempty"`
+	SinceDays       int32  `protobuf:"varint,3,opt,name=since_days,json=sinceDays,proto3" json:"since_days,omitempty"`
+	FoundAfter      string `protobuf:"bytes,4,opt,name=found_after,json=foundAfter,proto3" json:"found_after,omitempty"`
+	FoundBefore     string `protobuf:"bytes,5,opt,name=found_before,json=foundBefore,proto3" json:"found_before,omitempty"`
+	Authority       string `protobuf:"bytes,6,opt,name=authority,proto3" json:"authority,omitempty"`
+	// Any of them must match
+	Reason           []string `protobuf:"bytes,7,rep,name=reason,proto3" json:"reason,omitempty"`
+	LegalBasis       string   `protobuf:"bytes,8,opt,name=legal_basis,json=legalBasis,proto3" json:"legal_basis,omitempty"`
+	City             string   `protobuf:"bytes,9,opt,name=city,proto3" json:"city,omitempty"`
+	PostalCodePrefix string   `protobuf:"bytes,10,opt,name=postal_code_prefix,json=postalCodePrefix,proto3" json:"postal_code_prefix,omitempty"`
+	NameRegex        string   `protobuf:"bytes,11,opt,name=name_regex,json=nameRegex,proto3" json:"name_regex,omitempty"`
+	Dedup            bool     `protobuf:"varint,12,opt,name=dedup,proto3" json:"dedup,omitempty"`
+	// Bundesländer, e.g. bw, any of them must match
+	State []string `protobuf:"bytes,13,rep,name=state,proto3" json:"state,omitempty"`
+	// Matches soft-deleted items as well
+	IncludeDeleted bool `protobuf:"varint,14,opt,name=include_deleted,json=includeDeleted,proto3" json:"include_deleted,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_lmk_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_lmk_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_lmk_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Filter) GetPublishedAfter() string {
+	if x != nil {
+		return x.PublishedAfter
+	}
+	return ""
+}
+
+func (x *Filter) GetPublishedBefore() string {
+	if x != nil {
+		return x.PublishedBefore
+	}
+	return ""
+}
+
+func (x *Filter) GetSinceDays() int32 {
+	if x != nil {
+		return x.SinceDays
+	}
+	return 0
+}
+
+func (x *Filter) GetFoundAfter() string {
+	if x != nil {
+		return x.FoundAfter
+	}
+	return ""
+}
+
+func (x *Filter) GetFoundBefore() string {
+	if x != nil {
+		return x.FoundBefore
+	}
+	return ""
+}
+
+func (x *Filter) GetAuthority() string {
+	if x != nil {
+		return x.Authority
+	}
+	return ""
+}
+
+func (x *Filter) GetReason() []string {
+	if x != nil {
+		return x.Reason
+	}
+	return nil
+}
+
+func (x *Filter) GetLegalBasis() string {
+	if x != nil {
+		return x.LegalBasis
+	}
+	return ""
+}
+
+func (x *Filter) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *Filter) GetPostalCodePrefix() string {
+	if x != nil {
+		return x.PostalCodePrefix
+	}
+	return ""
+}
+
+func (x *Filter) GetNameRegex() string {
+	if x != nil {
+		return x.NameRegex
+	}
+	return ""
+}
+
+func (x *Filter) GetDedup() bool {
+	if x != nil {
+		return x.Dedup
+	}
+	return false
+}
+
+func (x *Filter) GetState() []string {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+func (x *Filter) GetIncludeDeleted() bool {
+	if x != nil {
+		return x.IncludeDeleted
+	}
+	return false
+}
+
+type ListItemsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *Filter                `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// One of authority, name, published, found, defaults to published
+	Sort   string `protobuf:"bytes,2,opt,name=sort,proto3" json:"sort,omitempty"`
+	Desc   bool   `protobuf:"varint,3,opt,name=desc,proto3" json:"desc,omitempty"`
+	Offset int32  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	// No limit if 0
+	Limit         int32 `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListItemsRequest) Reset() {
+	*x = ListItemsRequest{}
+	mi := &file_lmk_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListItemsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListItemsRequest) ProtoMessage() {}
+
+func (x *ListItemsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lmk_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListItemsRequest.ProtoReflect.Descriptor instead.
+func (*ListItemsRequest) Descriptor() ([]byte, []int) {
+	return file_lmk_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListItemsRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListItemsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListItemsRequest) GetDesc() bool {
+	if x != nil {
+		return x.Desc
+	}
+	return false
+}
+
+func (x *ListItemsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ListItemsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListItemsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Item                `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListItemsResponse) Reset() {
+	*x = ListItemsResponse{}
+	mi := &file_lmk_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListItemsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListItemsResponse) ProtoMessage() {}
+
+func (x *ListItemsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lmk_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListItemsResponse.ProtoReflect.Descriptor instead.
+func (*ListItemsResponse) Descriptor() ([]byte, []int) {
+	return file_lmk_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListItemsResponse) GetItems() []*Item {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type StreamNewItemsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filter        *Filter                `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamNewItemsRequest) Reset() {
+	*x = StreamNewItemsRequest{}
+	mi := &file_lmk_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamNewItemsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamNewItemsRequest) ProtoMessage() {}
+
+func (x *StreamNewItemsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lmk_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamNewItemsRequest.ProtoReflect.Descriptor instead.
+func (*StreamNewItemsRequest) Descriptor() ([]byte, []int) {
+	return file_lmk_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamNewItemsRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+var File_lmk_proto protoreflect.FileDescriptor
+
+const file_lmk_proto_rawDesc = "" +
+	"\n" +
+	"\tlmk.proto\x12\x06lmk.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xcd\x06\n" +
+	"\x04Item\x12\x1c\n" +
+	"\tauthority\x18\x01 \x01(\tR\tauthority\x12=\n" +
+	"\fpublished_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\vpublishedAt\x125\n" +
+	"\bfound_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\afoundAt\x12D\n" +
+	"\x10published_at_end\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0epublishedAtEnd\x12<\n" +
+	"\ffound_at_end\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"foundAtEnd\x12\x12\n" +
+	"\x04name\x18\x06 \x01(\tR\x04name\x12\x18\n" +
+	"\aaddress\x18\a \x01(\tR\aaddress\x12\x16\n" +
+	"\x06reason\x18\b \x01(\tR\x06reason\x12\x1f\n" +
+	"\vlegal_basis\x18\t \x01(\tR\n" +
+	"legalBasis\x12\x12\n" +
+	"\x04info\x18\n" +
+	" \x01(\tR\x04info\x12\x16\n" +
+	"\x06street\x18\v \x01(\tR\x06street\x12\x1f\n" +
+	"\vpostal_code\x18\f \x01(\tR\n" +
+	"postalCode\x12\x12\n" +
+	"\x04city\x18\r \x01(\tR\x04city\x12\x14\n" +
+	"\x05state\x18\x0e \x01(\tR\x05state\x12\x1a\n" +
+	"\blatitude\x18\x0f \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x10 \x01(\x01R\tlongitude\x129\n" +
+	"\n" +
+	"first_seen\x18\x11 \x01(\v2\x1a.google.protobuf.TimestampR\tfirstSeen\x127\n" +
+	"\tlast_seen\x18\x12 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x12\x1a\n" +
+	"\bvanished\x18\x13 \x01(\bR\bvanished\x12\x17\n" +
+	"\amap_url\x18\x14 \x01(\tR\x06mapUrl\x121\n" +
+	"\x14authority_normalized\x18\x15 \x01(\tR\x13authorityNormalized\x129\n" +
+	"\n" +
+	"deleted_at\x18\x16 \x01(\v2\x1a.google.protobuf.TimestampR\tdeletedAt\"\xcc\x03\n" +
+	"\x06Filter\x12'\n" +
+	"\x0fpublished_after\x18\x01 \x01(\tR\x0epublishedAfter\x12)\n" +
+	"\x10published_before\x18\x02 \x01(\tR\x0fpublishedBefore\x12\x1d\n" +
+	"\n" +
+	"since_days\x18\x03 \x01(\x05R\tsinceDays\x12\x1f\n" +
+	"\vfound_after\x18\x04 \x01(\tR\n" +
+	"foundAfter\x12!\n" +
+	"\ffound_before\x18\x05 \x01(\tR\vfoundBefore\x12\x1c\n" +
+	"\tauthority\x18\x06 \x01(\tR\tauthority\x12\x16\n" +
+	"\x06reason\x18\a \x03(\tR\x06reason\x12\x1f\n" +
+	"\vlegal_basis\x18\b \x01(\tR\n" +
+	"legalBasis\x12\x12\n" +
+	"\x04city\x18\t \x01(\tR\x04city\x12,\n" +
+	"\x12postal_code_prefix\x18\n" +
+	" \x01(\tR\x10postalCodePrefix\x12\x1d\n" +
+	"\n" +
+	"name_regex\x18\v \x01(\tR\tnameRegex\x12\x14\n" +
+	"\x05dedup\x18\f \x01(\bR\x05dedup\x12\x14\n" +
+	"\x05state\x18\r \x03(\tR\x05state\x12'\n" +
+	"\x0finclude_deleted\x18\x0e \x01(\bR\x0eincludeDeleted\"\x90\x01\n" +
+	"\x10ListItemsRequest\x12&\n" +
+	"\x06filter\x18\x01 \x01(\v2\x0e.lmk.v1.FilterR\x06filter\x12\x12\n" +
+	"\x04sort\x18\x02 \x01(\tR\x04sort\x12\x12\n" +
+	"\x04desc\x18\x03 \x01(\bR\x04desc\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05limit\x18\x05 \x01(\x05R\x05limit\"7\n" +
+	"\x11ListItemsResponse\x12\"\n" +
+	"\x05items\x18\x01 \x03(\v2\f.lmk.v1.ItemR\x05items\"?\n" +
+	"\x15StreamNewItemsRequest\x12&\n" +
+	"\x06filter\x18\x01 \x01(\v2\x0e.lmk.v1.FilterR\x06filter2\x8a\x01\n" +
+	"\x05Items\x12@\n" +
+	"\tListItems\x12\x18.lmk.v1.ListItemsRequest\x1a\x19.lmk.v1.ListItemsResponse\x12?\n" +
+	"\x0eStreamNewItems\x12\x1d.lmk.v1.StreamNewItemsRequest\x1a\f.lmk.v1.Item0\x01B#Z!github.com/leonklingele/lmk/lmkpbb\x06proto3"
+
+var (
+	file_lmk_proto_rawDescOnce sync.Once
+	file_lmk_proto_rawDescData []byte
+)
+
+func file_lmk_proto_rawDescGZIP() []byte {
+	file_lmk_proto_rawDescOnce.Do(func() {
+		file_lmk_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_lmk_proto_rawDesc), len(file_lmk_proto_rawDesc)))
+	})
+	return file_lmk_proto_rawDescData
+}
+
+var file_lmk_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_lmk_proto_goTypes = []any{
+	(*Item)(nil),                  // 0: lmk.v1.Item
+	(*Filter)(nil),                // 1: lmk.v1.Filter
+	(*ListItemsRequest)(nil),      // 2: lmk.v1.ListItemsRequest
+	(*ListItemsResponse)(nil),     // 3: lmk.v1.ListItemsResponse
+	(*StreamNewItemsRequest)(nil), // 4: lmk.v1.StreamNewItemsRequest
+	(*timestamppb.Timestamp)(nil), // 5: google.protobuf.Timestamp
+}
+var file_lmk_proto_depIdxs = []int32{
+	5,  // 0: lmk.v1.Item.published_at:type_name -> google.protobuf.Timestamp
+	5,  // 1: lmk.v1.Item.found_at:type_name -> google.protobuf.Timestamp
+	5,  // 2: lmk.v1.Item.published_at_end:type_name -> google.protobuf.Timestamp
+	5,  // 3: lmk.v1.Item.found_at_end:type_name -> google.protobuf.Timestamp
+	5,  // 4: lmk.v1.Item.first_seen:type_name -> google.protobuf.Timestamp
+	5,  // 5: lmk.v1.Item.last_seen:type_name -> google.protobuf.Timestamp
+	5,  // 6: lmk.v1.Item.deleted_at:type_name -> google.protobuf.Timestamp
+	1,  // 7: lmk.v1.ListItemsRequest.filter:type_name -> lmk.v1.Filter
+	0,  // 8: lmk.v1.ListItemsResponse.items:type_name -> lmk.v1.Item
+	1,  // 9: lmk.v1.StreamNewItemsRequest.filter:type_name -> lmk.v1.Filter
+	2,  // 10: lmk.v1.Items.ListItems:input_type -> lmk.v1.ListItemsRequest
+	4,  // 11: lmk.v1.Items.StreamNewItems:input_type -> lmk.v1.StreamNewItemsRequest
+	3,  // 12: lmk.v1.Items.ListItems:output_type -> lmk.v1.ListItemsResponse
+	0,  // 13: lmk.v1.Items.StreamNewItems:output_type -> lmk.v1.Item
+	12, // [12:14] is the sub-list for method output_type
+	10, // [10:12] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_lmk_proto_init() }
+func file_lmk_proto_init() {
+	if File_lmk_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_lmk_proto_rawDesc), len(file_lmk_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_lmk_proto_goTypes,
+		DependencyIndexes: file_lmk_proto_depIdxs,
+		MessageInfos:      file_lmk_proto_msgTypes,
+	}.Build()
+	File_lmk_proto = out.File
+	file_lmk_proto_goTypes = nil
+	file_lmk_proto_depIdxs = nil
+}
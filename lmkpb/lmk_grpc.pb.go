@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: lmk.proto
+
+package lmkpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Items_ListItems_FullMethodName      = "/lmk.v1.Items/ListItems"
+	Items_StreamNewItems_FullMethodName = "/lmk.v1.Items/StreamNewItems"
+)
+
+// ItemsClient is the client API for Items service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Serves the stored items, see the grpc subcommand of lmk.
+type ItemsClient interface {
+	// Lists the stored items matching the filter.
+	ListItems(ctx context.Context, in *ListItemsRequest, opts ...grpc.CallOption) (*ListItemsResponse, error)
+	// Streams the items matching the filter as they're discovered by the
+	// server's scrapes, see its -watch flag.
+	StreamNewItems(ctx context.Context, in *StreamNewItemsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Item], error)
+}
+
+type itemsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewItemsClient(cc grpc.ClientConnInterface) ItemsClient {
+	return &itemsClient{cc}
+}
+
+func (c *itemsClient) ListItems(ctx context.Context, in *ListItemsRequest, opts ...grpc.CallOption) (*ListItemsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListItemsResponse)
+	err := c.cc.Invoke(ctx, Items_ListItems_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *itemsClient) StreamNewItems(ctx context.Context, in *StreamNewItemsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Item], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Items_ServiceDesc.Streams[0], Items_StreamNewItems_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamNewItemsRequest, Item]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Items_StreamNewItemsClient = grpc.ServerStreamingClient[Item]
+
+// ItemsServer is the server API for Items service.
+// All implementations must embed UnimplementedItemsServer
+// for forward compatibility.
+//
+// Serves the stored items, see the grpc subcommand of lmk.
+type ItemsServer interface {
+	// Lists the stored items matching the filter.
+	ListItems(context.Context, *ListItemsRequest) (*ListItemsResponse, error)
+	// Streams the items matching the filter as they're discovered by the
+	// server's scrapes, see its -watch flag.
+	StreamNewItems(*StreamNewItemsRequest, grpc.ServerStreamingServer[Item]) error
+	mustEmbedUnimplementedItemsServer()
+}
+
+// UnimplementedItemsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedItemsServer struct{}
+
+func (UnimplementedItemsServer) ListItems(context.Context, *ListItemsRequest) (*ListItemsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListItems not implemented")
+}
+func (UnimplementedItemsServer) StreamNewItems(*StreamNewItemsRequest, grpc.ServerStreamingServer[Item]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamNewItems not implemented")
+}
+func (UnimplementedItemsServer) mustEmbedUnimplementedItemsServer() {}
+func (UnimplementedItemsServer) testEmbeddedByValue()               {}
+
+// UnsafeItemsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ItemsServer will
+// result in compilation errors.
+type UnsafeItemsServer interface {
+	mustEmbedUnimplementedItemsServer()
+}
+
+func RegisterItemsServer(s grpc.ServiceRegistrar, srv ItemsServer) {
+	// If the following call pancis, it indicates UnimplementedItemsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Items_ServiceDesc, srv)
+}
+
+func _Items_ListItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListItemsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ItemsServer).ListItems(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Items_ListItems_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ItemsServer).ListItems(ctx, req.(*ListItemsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Items_StreamNewItems_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamNewItemsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ItemsServer).StreamNewItems(m, &grpc.GenericServerStream[StreamNewItemsRequest, Item]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Items_StreamNewItemsServer = grpc.ServerStreamingServer[Item]
+
+// Items_ServiceDesc is the grpc.ServiceDesc for Items service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Items_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lmk.v1.Items",
+	HandlerType: (*ItemsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListItems",
+			Handler:    _Items_ListItems_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamNewItems",
+			Handler:       _Items_StreamNewItems_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "lmk.proto",
+}
@@ -4,76 +4,63 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
-	"database/sql"
-	"encoding/gob"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag" //nolint:depguard // We only allow to import the flag package in here
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/prometheus/client_golang/prometheus"
 
 	_ "modernc.org/sqlite"
 )
 
 const (
-	tableMaxWidth    = 42
-	tableShowDetails = true
+	// Of the table cells, see -max-width
+	defaultTableMaxWidth = 42
 
-	timeFormat = "02.01.2006"
+	timeFormat      = "02.01.2006"
+	timestampFormat = "02.01.2006 15:04:05"
 
-	requestTimeout = 10 * time.Second
+	// Of each HTTP request, see -timeout
+	defaultRequestTimeout = 10 * time.Second
+	// Of scraping all pages of a source, see -scrape-timeout
+	defaultScrapeTimeout = 5 * time.Minute
+
+	// Of the serve subcommand, only reachable locally by default
+	defaultListenAddr = "localhost:8080"
 
 	lmkURL = "https://verbraucherinfo-bw.de/,Lde/Startseite/Lebensmittelkontrolle"
-)
 
-const (
-	defaultSQLiteFilePath = "./db.sqlite"
-
-	//nolint:dupword // False positive
-	sqliteInitStmt = `
-		begin;
-		create table items (
-			id integer primary key not null,
-			hash text text unique not null,
-			authority text not null,
-			published_at datetime not null,
-			found_at datetime not null,
-			name text not null,
-			address text not null,
-			reason text not null,
-			legal_basis text not null,
-			info text not null
-		) strict;
-		commit;
-	`
-	sqliteInsertStmt = `
-		insert into items (
-			hash,
-			authority,
-			published_at,
-			found_at,
-			name,
-			address,
-			reason,
-			legal_basis,
-			info
-		) values (
-			?, ?, ?, ?, ?, ?, ?, ?, ?
-		);
-	`
+	// Identifies us and tells whom to contact, some sites block Go's
+	// default user agent
+	defaultUserAgent = "lmk (+https://github.com/leonklingele/lmk)"
 )
 
 //nolint:gochecknoglobals // Nice to use as a global
 var logTarget = os.Stderr
 
+// Matches a range of two dates in timeFormat, e.g. "10.06.2025 bis
+// 25.06.2025"
+var dateRangeRegexp = regexp.MustCompile(`^(\d{2}\.\d{2}\.\d{4})\s*(?:/|-|–|und|bis)\s*(\d{2}\.\d{2}\.\d{4})$`)
+
 func trimText(t string) string {
 	return strings.Trim(t, " \t\r\n")
 }
@@ -84,20 +71,157 @@ type item struct {
 	PublishedAtStr string    `json:"-"`
 	FoundAt        time.Time `json:"found_at"`
 	FoundAtStr     string    `json:"-"`
+	// Ends of date ranges, zero for a single date
+	PublishedAtEnd time.Time `json:"published_at_end"`
+	FoundAtEnd     time.Time `json:"found_at_end"`
 	Name           string    `json:"name"`
 	Address        string    `json:"address"`
 	Reason         string    `json:"reason"`
 	LegalBasis     string    `json:"legal_basis"`
 	Info           string    `json:"info"`
+
+	// Parsed from the address, all empty if it couldn't be parsed
+	Street     string `json:"street"`
+	PostalCode string `json:"postal_code"`
+	City       string `json:"city"`
+	// Bundesland of the source the item was scraped from
+	State string `json:"state"`
+	// Of the address, both zero if not geocoded, see -geocode
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	// Searches the address on a map, empty if there's no address
+	MapURL string `json:"map_url"`
+	// When the item was first stored in the database, zero if unknown
+	FirstSeen time.Time `json:"first_seen"`
+	// When the item was last scraped, zero if unknown
+	LastSeen time.Time `json:"last_seen"`
+	// Whether the item is stored but no longer published
+	Vanished bool `json:"vanished,omitempty"`
+	// Canonical name of the authority, see authorityNormalizer, the
+	// authority itself if it has none
+	AuthorityNormalized string `json:"authority_normalized"`
+	// When the item was soft-deleted, zero unless it was, see
+	// -soft-delete
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// jsonItem is the JSON representation of an item, see item.MarshalJSON.
+// It has the keys of item in the same order, its unknown times are
+// omitted.
+type jsonItem struct {
+	Authority           string     `json:"authority"`
+	PublishedAt         *time.Time `json:"published_at,omitempty"`
+	FoundAt             *time.Time `json:"found_at,omitempty"`
+	PublishedAtEnd      *time.Time `json:"published_at_end,omitempty"`
+	FoundAtEnd          *time.Time `json:"found_at_end,omitempty"`
+	Name                string     `json:"name"`
+	Address             string     `json:"address"`
+	Reason              string     `json:"reason"`
+	LegalBasis          string     `json:"legal_basis"`
+	Info                string     `json:"info"`
+	Street              string     `json:"street"`
+	PostalCode          string     `json:"postal_code"`
+	City                string     `json:"city"`
+	State               string     `json:"state"`
+	Latitude            float64    `json:"latitude,omitempty"`
+	Longitude           float64    `json:"longitude,omitempty"`
+	MapURL              string     `json:"map_url"`
+	FirstSeen           *time.Time `json:"first_seen,omitempty"`
+	LastSeen            *time.Time `json:"last_seen,omitempty"`
+	Vanished            bool       `json:"vanished,omitempty"`
+	AuthorityNormalized string     `json:"authority_normalized"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty"`
+}
+
+// MarshalJSON encodes itm with its unknown times omitted, rather than
+// encoded as the zero time, 0001-01-01T00:00:00Z. They're decoded as the
+// zero time again.
+func (itm *item) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(jsonItem{
+		Authority:           itm.Authority,
+		PublishedAt:         optionalTime(itm.PublishedAt),
+		FoundAt:             optionalTime(itm.FoundAt),
+		PublishedAtEnd:      optionalTime(itm.PublishedAtEnd),
+		FoundAtEnd:          optionalTime(itm.FoundAtEnd),
+		Name:                itm.Name,
+		Address:             itm.Address,
+		Reason:              itm.Reason,
+		LegalBasis:          itm.LegalBasis,
+		Info:                itm.Info,
+		Street:              itm.Street,
+		PostalCode:          itm.PostalCode,
+		City:                itm.City,
+		State:               itm.State,
+		Latitude:            itm.Latitude,
+		Longitude:           itm.Longitude,
+		MapURL:              itm.MapURL,
+		FirstSeen:           optionalTime(itm.FirstSeen),
+		LastSeen:            optionalTime(itm.LastSeen),
+		Vanished:            itm.Vanished,
+		AuthorityNormalized: itm.AuthorityNormalized,
+		DeletedAt:           optionalTime(itm.DeletedAt),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON-encode item: %w", err)
+	}
+	return b, nil
+}
+
+// optionalTime returns a pointer to t, nil if t is the zero time, i.e.
+// unknown.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
 }
 
-func sel2item(s *goquery.Selection) (*item, error) {
+// itemHash returns the hex-encoded SHA-256 hash identifying itm. Only
+// the scraped fields identify an item, in a fixed order and each prefixed
+// with its length so the hash neither depends on the item type nor is
+// ambiguous. The date strings are excluded as they are kept for display
+// only, so are range ends, items with a date range were identified by
+// its start before. The address parts are derived from the hashed
+// address.
+//
+// Hashes were the SHA-256 of the gob-encoded item before, stored items
+// are rehashed by a migration, see rehashItemsMigration.
+func itemHash(itm *item) string {
+	h := sha256.New()
+	for _, v := range []string{
+		itm.State,
+		itm.Authority,
+		hashDate(itm.PublishedAt),
+		hashDate(itm.FoundAt),
+		itm.Name,
+		itm.Address,
+		itm.Reason,
+		itm.LegalBasis,
+		itm.Info,
+	} {
+		h.Write([]byte(strconv.Itoa(len(v)) + ":" + v + ";"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashDate formats the date t for itemHash, regardless of its location.
+func hashDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.DateOnly)
+}
+
+// selTexts returns the trimmed texts of the selected elements, of which
+// there must be want.
+func selTexts(s *goquery.Selection, want int) ([]string, error) {
 	var ss []string
 	s.Each(func(_ int, s *goquery.Selection) {
 		ss = append(ss, trimText(s.Text()))
 	})
 
-	if got, want := len(ss), 8; got != want {
+	if got := len(ss); got != want {
 		details, err := s.Html()
 		if err != nil {
 			details = err.Error()
@@ -105,314 +229,1577 @@ func sel2item(s *goquery.Selection) (*item, error) {
 		return nil, fmt.Errorf("invalid number of parts found %d/%d: %s", got, want, details)
 	}
 
-	for i, s := range ss {
-		ss[i] = trimText(s)
-	}
+	return ss, nil
+}
 
-	authority,
-		publishedAtStr,
-		name,
-		address,
-		foundAtStr,
-		reason,
-		legalBasis,
-		info := ss[0],
-		ss[1],
-		ss[2],
-		ss[3],
-		ss[4],
-		ss[5],
-		ss[6],
-		ss[7]
+// texts2item creates an item of src from the texts of a row's cells.
+// It's safe for concurrent use, unlike goquery selections.
+func texts2item(src *source, ss []string) (*item, error) {
+	itm := &item{
+		State: src.state,
+	}
+	for i, c := range src.columns {
+		c.field.set(itm, ss[i])
+	}
+	setAddressParts(itm)
+	itm.AuthorityNormalized = src.authorities.normalize(itm.Authority)
 
-	foundAtStr = strings.TrimSuffix(foundAtStr, "z")   // Theres one item with a trailing "z"
-	foundAtStr = strings.Split(foundAtStr, " und ")[0] // Theres one item with multiple dates
+	itm.FoundAtStr = strings.TrimSuffix(itm.FoundAtStr, "z") // Theres one item with a trailing "z"
 
-	itm := &item{
-		Authority:      authority,
-		PublishedAtStr: publishedAtStr,
-		FoundAtStr:     foundAtStr,
-		Name:           name,
-		Address:        address,
-		Reason:         reason,
-		LegalBasis:     legalBasis,
-		Info:           info,
-	}
-
-	if strings.Contains(publishedAtStr, ".") { // Looks like a date
-		publishedAt, err := time.Parse(timeFormat, publishedAtStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse published at %q: %w", publishedAtStr, err)
-		}
-		itm.PublishedAt = publishedAt
+	// Inspections may span several days, the date strings keep the
+	// start only
+	var publishedAtEndStr, foundAtEndStr string
+	itm.PublishedAtStr, publishedAtEndStr = splitDateRange(itm.PublishedAtStr)
+	itm.FoundAtStr, foundAtEndStr = splitDateRange(itm.FoundAtStr)
+	if foundAtEndStr == "" {
+		itm.FoundAtStr = strings.Split(itm.FoundAtStr, " und ")[0] // Theres one item with multiple dates
 	}
 
-	if strings.Contains(foundAtStr, ".") { // Looks like a date
-		foundAt, err := time.Parse(timeFormat, foundAtStr)
+	for _, d := range []struct {
+		name string
+		s    string
+		dst  *time.Time
+	}{
+		{"published at", itm.PublishedAtStr, &itm.PublishedAt},
+		{"published at end", publishedAtEndStr, &itm.PublishedAtEnd},
+		{"found at", itm.FoundAtStr, &itm.FoundAt},
+		{"found at end", foundAtEndStr, &itm.FoundAtEnd},
+	} {
+		if !strings.Contains(d.s, ".") { // Doesn't look like a date
+			continue
+		}
+		t, err := time.Parse(timeFormat, d.s)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse found at %q: %w", foundAtStr, err)
+			return nil, fmt.Errorf("failed to parse %s %q: %w", d.name, d.s, err)
 		}
-		itm.FoundAt = foundAt
+		*d.dst = t
 	}
 
 	return itm, nil
 }
 
-func loadItems(ctx context.Context, requestTimeout time.Duration, l *slog.Logger) ([]*item, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lmkURL, http.NoBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// splitDateRange splits a range of two dates such as "27.03.2025 /
+// 28.03.2025" into its start and end. The end is empty if s isn't a
+// range.
+func splitDateRange(s string) (string, string) {
+	m := dateRangeRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return s, ""
 	}
+	return m[1], m[2]
+}
 
-	res, err := (&http.Client{
-		Timeout: requestTimeout,
-	}).Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get: %w", err)
-	}
-	defer func() {
-		if err := res.Body.Close(); err != nil {
-			l.ErrorContext(ctx, fmt.Errorf("failed to close body: %w", err).Error())
-		}
-	}()
+// loadOptions configures how loadItems fetches the page.
+type loadOptions struct {
+	// Local file to read the page from instead of fetching url
+	file      string
+	url       string
+	userAgent string
+	// Of each request, retries get their own, see fetchTimeout
+	timeout time.Duration
+	// Of the whole scrape, all pages along with their retries, see
+	// scrapeSource
+	scrapeTimeout time.Duration
+	// Maximum number of requests made, retrying transient failures
+	attempts int
+	// Directory to save the fetched page to, don't save it if empty
+	snapshotDir string
+	// Overrides the proxy configured in the environment if non-nil
+	proxy *url.URL
+	// Validators of the page fetched last, the page is fetched
+	// unconditionally if empty
+	validators pageValidators
+	// Only warn about unexpected labels in the table heading
+	lenient bool
+	// Spaces the requests, shared by all scrapes, may be nil
+	limiter *fetchLimiter
+	// Records the scrape, may be nil
+	metrics *scrapeMetrics
+}
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+// validateHTTPURL checks that s is an absolute http(s) URL, name
+// describes it in errors, e.g. "source".
+func validateHTTPURL(name, s string) error {
+	u, err := url.Parse(s)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create document: %w", err)
+		return fmt.Errorf("failed to parse %s URL: %w", name, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s URL %q, expected an absolute http(s) URL", name, s)
 	}
+	return nil
+}
+
+// loadItems fetches or reads the page of src and parses its items,
+// following the links to subsequent pages of a paginated table. It
+// returns the validators of the first fetched page as well.
+func loadItems(
+	ctx context.Context,
+	l *slog.Logger,
+	src *source,
+	opts loadOptions,
+) ([]*item, pageValidators, error) {
+	start := time.Now()
+	defer func() { opts.metrics.observeScrape(time.Since(start)) }()
 
-	tbl := doc.Find(`#consumerInfoTable`)
+	// Each attempt of fetchPage is bounded by opts.timeout
+	client := &http.Client{Transport: newProxyTransport(opts.proxy)}
 
-	// Sanity check
-	hl, err := sel2item(tbl.Find(`thead th p`))
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve table heading: %w", err)
-	}
-	if hl.Authority != "Behörde" ||
-		hl.PublishedAtStr != "Datum Veröffentlichung" ||
-		hl.FoundAtStr != "Feststellungstag" ||
-		hl.Name != "Betriebsbezeichnung" ||
-		hl.Address != "Anschrift" ||
-		hl.Reason != "Sachverhalt/Grund der Beanstandung" ||
-		hl.LegalBasis != "Rechtsgrundlage" ||
-		hl.Info != "Hinweise zur Mängelbeseitigung und Bemerkungen" {
-		return nil, fmt.Errorf("labels incorrect, has the page design changed? %+v", hl)
+	var (
+		body       []byte
+		validators pageValidators
+	)
+	if opts.file != "" {
+		b, err := readPageFile(opts.file)
+		if err != nil {
+			return nil, pageValidators{}, err
+		}
+		body = b
+	} else {
+		b, v, err := fetchPage(ctx, l, client, opts)
+		if err != nil {
+			return nil, pageValidators{}, err
+		}
+		body, validators = b, v
 	}
 
 	var items []*item
-	errch := make(chan error, 1)
-	tbl.
-		Find(`tbody tr`).
-		EachWithBreak(func(_ int, s *goquery.Selection) bool {
-			itm, err := sel2item(s.Find(`td`))
-			if err != nil {
-				details, err2 := s.Html()
-				if err2 != nil {
-					details = err2.Error()
-				}
-				errch <- fmt.Errorf("failed to retrieve item from selection %s: %w", details, err)
-				return false
+	pageURL := opts.url
+	visited := map[string]bool{pageURL: true}
+	for page := 1; ; page++ {
+		if opts.snapshotDir != "" && opts.file == "" {
+			// Not fatal, the snapshot is for debugging only
+			if path, err := saveSnapshot(opts.snapshotDir, time.Now(), body); err != nil {
+				l.WarnContext(ctx, err.Error())
+			} else {
+				l.DebugContext(ctx, "saved snapshot", "path", path)
 			}
+		}
 
-			items = append(items, itm)
-			return true
-		})
-	close(errch)
-	if err := <-errch; err != nil {
-		return nil, err
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, pageValidators{}, fmt.Errorf("failed to create document of page %d: %w", page, err)
+		}
+
+		pageItems, err := parseItems(ctx, l, src, doc, opts.lenient)
+		if err != nil {
+			opts.metrics.addParseError()
+			return nil, pageValidators{}, fmt.Errorf("failed to parse page %d: %w", page, err)
+		}
+		items = append(items, pageItems...)
+
+		next, ok, err := nextPageURL(src, doc, pageURL)
+		if err != nil {
+			return nil, pageValidators{}, err
+		}
+		if !ok || visited[next] {
+			break
+		}
+		if opts.file != "" {
+			l.WarnContext(
+				ctx,
+				"not following pagination of a local file, items of subsequent pages are missing",
+				"next", next,
+			)
+			break
+		}
+		if page >= sourceMaxPages {
+			l.WarnContext(
+				ctx,
+				"stopped following pagination, items of subsequent pages are missing",
+				"pages", page,
+			)
+			break
+		}
+		visited[next] = true
+
+		l.DebugContext(ctx, "following pagination", "page", page+1, "url", next)
+		nextOpts := opts
+		nextOpts.url = next
+		nextOpts.validators = pageValidators{}
+		b, _, err := fetchPage(ctx, l, client, nextOpts)
+		if err != nil {
+			return nil, pageValidators{}, fmt.Errorf("failed to fetch page %d: %w", page+1, err)
+		}
+		body, pageURL = b, next
 	}
 
 	// Order by published at
 	slices.SortStableFunc(items, func(a, b *item) int {
 		return a.PublishedAt.Compare(b.PublishedAt)
 	})
+	opts.metrics.addScraped(len(items))
+
+	return items, validators, nil
+}
+
+// parseItems parses the items of a single page of src. Unexpected
+// labels in the table heading fail unless lenient is set, an unexpected
+// number of columns always does.
+func parseItems(
+	ctx context.Context,
+	l *slog.Logger,
+	src *source,
+	doc *goquery.Document,
+	lenient bool,
+) ([]*item, error) {
+	tbl := doc.Find(src.tableSelector)
+
+	// Sanity check
+	hl, err := selTexts(tbl.Find(src.headSelector), len(src.columns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve table heading: %w", err)
+	}
+	for i, c := range src.columns {
+		if hl[i] == c.label {
+			continue
+		}
+		if !lenient {
+			return nil, fmt.Errorf("labels incorrect, has the page design changed? %q", hl)
+		}
+		l.WarnContext(
+			ctx,
+			"unexpected label, has the page design changed?",
+			"column", i+1,
+			"label", hl[i],
+			"expected", c.label,
+		)
+	}
+
+	// The selections aren't safe for concurrent use, extract their texts
+	// up front
+	rows := tbl.Find(src.rowSelector)
+	texts := make([][]string, rows.Length())
+	var err2 error
+	rows.EachWithBreak(func(i int, s *goquery.Selection) bool {
+		ss, err := selTexts(s.Find(src.cellSelector), len(src.columns))
+		if err != nil {
+			err2 = rowError(s, err)
+			return false
+		}
+		texts[i] = ss
+		return true
+	})
+	if err2 != nil {
+		return nil, err2
+	}
+
+	items, errs := parseRows(src, texts)
+	for i, err := range errs {
+		if err != nil {
+			return nil, rowError(rows.Eq(i), err)
+		}
+	}
 
 	return items, nil
 }
 
-func capstring(s string, l int) string { //nolint:unparam // False positive
-	if len(s) <= l {
+// parseRows creates the items from the texts of the rows concurrently,
+// preserving their order. The error of a row, if any, is at the row's
+// index.
+func parseRows(src *source, texts [][]string) ([]*item, []error) {
+	items := make([]*item, len(texts))
+	errs := make([]error, len(texts))
+
+	rowc := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(texts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rowc {
+				items[i], errs[i] = texts2item(src, texts[i])
+			}
+		}()
+	}
+	for i := range texts {
+		rowc <- i
+	}
+	close(rowc)
+	wg.Wait()
+
+	return items, errs
+}
+
+func rowError(s *goquery.Selection, err error) error {
+	details, err2 := s.Html()
+	if err2 != nil {
+		details = err2.Error()
+	}
+	return fmt.Errorf("failed to retrieve item from selection %s: %w", details, err)
+}
+
+// formatDate formats t in timeFormat, the zero time is formatted as an
+// empty string.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(timeFormat)
+}
+
+// formatTimestamp formats t in timestampFormat, the zero time is
+// formatted as an empty string.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(timestampFormat)
+}
+
+// capstring truncates s to its first l characters followed by an
+// ellipsis. Characters, not bytes, are counted to not split multi-byte
+// ones such as umlauts.
+func capstring(s string, l int) string {
+	if utf8.RuneCountInString(s) <= l {
 		return s
 	}
-	return s[:l] + "…"
+	return string([]rune(s)[:l]) + "…"
 }
 
-func run( //nolint:revive // They are bool-options
+// runOptions selects the items printed by run. All scraped items are
+// printed if none is set.
+type runOptions struct {
+	// Print the items which haven't been stored before
+	newOnly bool
+	// Print the stored items which are no longer published, after the
+	// new ones if newOnly is set as well
+	vanished bool
+	// Print the new, reappeared and vanished items compared to the
+	// previous scrape in sections, excludes newOnly and vanished
+	diff bool
+	// File caching the validators of the page fetched last, which is
+	// fetched unconditionally if empty
+	httpCacheFile string
+	// Geocode the addresses of the items before storing them
+	geocode bool
+	// Record the changes of the new items of businesses stored before,
+	// see storage.recordHistory
+	history bool
+	// File caching the items scraped last, which aren't cached if empty
+	resultCacheFile string
+	// Of the cached items, which are scraped again once expired
+	resultCacheTTL time.Duration
+	// Don't output anything if no items match, e.g. when watching
+	skipEmptyOutput bool
+	// Don't output the items at all, e.g. when streaming them instead
+	discardOutput bool
+	// Record the scrapes by the state of their source, may be nil
+	metrics map[string]*scrapeMetrics
+	// Notified about the new items matching the filter, regardless of its
+	// offset and limit, if newOnly or diff is set
+	notifiers []notifier
+}
+
+func run(
 	ctx context.Context,
 	l *slog.Logger,
-	sqliteFile string,
-	newOnly,
-	printAsJSON bool,
+	storageCfg storageConfig,
+	srcs []*source,
+	load loadOptions,
+	opts runOptions,
+	f *filter,
+	out outputOptions,
 ) error {
-	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
-	defer cancel()
+	if opts.httpCacheFile != "" {
+		v, err := loadPageValidators(opts.httpCacheFile)
+		if err != nil {
+			return err
+		}
+		load.validators = v
+	}
 
-	items, err := loadItems(ctx, requestTimeout, l)
-	if err != nil {
+	var (
+		items      []*item
+		scraped    = srcs
+		validators pageValidators
+		fromCache  bool
+		// Of some of several sources, returned once the items of the
+		// others are processed
+		srcErr error
+		err    error
+	)
+	if len(srcs) == 1 {
+		var res sourceResult
+		res, err = scrapeSource(ctx, l, srcs[0], load, opts)
+		items, validators, fromCache = res.items, res.validators, res.fromCache
+	} else {
+		items, scraped, srcErr = scrapeSources(ctx, l, srcs, load, opts)
+		if len(scraped) == 0 {
+			err = srcErr
+		}
+	}
+	if errors.Is(err, errPageNotModified) {
+		l.InfoContext(ctx, "page not modified, skipping")
+		opts.metrics[srcs[0].state].setSuccess(time.Now())
+		return nil
+	} else if err != nil {
 		return err
 	}
+	if opts.httpCacheFile != "" && !fromCache {
+		l.InfoContext(ctx, "page modified")
+	}
 
-	if newOnly {
-		var isFirstRun bool
-		if _, err := os.Stat(sqliteFile); os.IsNotExist(err) {
-			isFirstRun = true
+	if opts.geocode {
+		if err := setItemCoordinates(ctx, l, out.geocoder, items); err != nil {
+			return err
 		}
+	}
 
-		db, err := sql.Open("sqlite", sqliteFile)
+	var sections []diffSection
+	if opts.newOnly || opts.vanished || opts.diff {
+		st, err := openStorage(ctx, l, storageCfg)
 		if err != nil {
-			return fmt.Errorf("failed to open sqlite database: %w", err)
+			return err
 		}
+		defer st.close(ctx, l)
 
-		if isFirstRun {
-			if _, err := db.Exec(sqliteInitStmt); err != nil {
-				return fmt.Errorf("failed to init database: %w", err)
+		// Of the sources scraped only, the items of the others would
+		// seem to have vanished
+		var previous []*item
+		if opts.diff {
+			for _, src := range scraped {
+				p, err := st.lastSeenItems(ctx, l, src.state)
+				if err != nil {
+					return err
+				}
+				previous = append(previous, p...)
 			}
-			l.InfoContext(ctx, "successfully initialized database")
 		}
 
-		stmt, err := db.Prepare(sqliteInsertStmt)
+		seenAt := storageNow()
+		newItems, err := st.storeItems(ctx, l, seenAt, items)
 		if err != nil {
-			return fmt.Errorf("failed to prepare insert statement: %w", err)
+			return err
+		}
+		for _, itm := range newItems {
+			opts.metrics[itm.State].addNew(1)
 		}
-		defer func() {
-			if err := stmt.Close(); err != nil {
-				l.ErrorContext(ctx, fmt.Errorf("failed to close insert statement: %w", err).Error())
+		if opts.history {
+			// Not fatal, the items are stored
+			if _, err := st.recordHistory(ctx, l, seenAt, newItems); err != nil {
+				l.ErrorContext(ctx, err.Error())
 			}
-		}()
+		}
+		if opts.newOnly || opts.diff {
+			// All of them, -offset and -limit only page the output
+			notifyItems(ctx, l, opts.notifiers, f.matching(newItems))
+		}
+		if opts.diff {
+			sections = diffItems(items, newItems, previous)
+		}
 
-		newItems := make([]*item, 0, len(items))
-		for _, itm := range items {
-			var buf bytes.Buffer
-			if err := gob.NewEncoder(&buf).Encode(itm); err != nil {
-				return fmt.Errorf("failed to gob-encode item %+v: %w", itm, err)
+		items = nil
+		if opts.newOnly {
+			items = newItems
+		}
+		if opts.vanished {
+			for _, src := range scraped {
+				vanished, err := st.vanishedItems(ctx, l, src.state, seenAt)
+				if err != nil {
+					return err
+				}
+				items = append(items, vanished...)
 			}
+		}
+	}
 
-			hash := sha256.Sum256(buf.Bytes())
-
-			if _, err := stmt.Exec(
-				hex.EncodeToString(hash[:]),
-				itm.Authority,
-				itm.PublishedAt,
-				itm.FoundAt,
-				itm.Name,
-				itm.Address,
-				itm.Reason,
-				itm.LegalBasis,
-				itm.Info,
-			); err != nil {
-				// TODO: Properly check for error, see https://gitlab.com/cznic/sqlite/-/blob/f49aba7eddcec7d31797e72c67aafb0398970730/all_test.go#L2228
-				if got, want := err.Error(), "constraint failed: UNIQUE constraint failed: items.hash (2067)"; got == want {
-					// This is fine
-					continue
-				}
+	switch {
+	case opts.discardOutput:
+	case opts.diff:
+		var n int
+		for i := range sections {
+			sections[i].items = f.apply(sections[i].items)
+			n += len(sections[i].items)
+		}
+		if n > 0 || !opts.skipEmptyOutput {
+			if err := renderDiff(ctx, l, sections, out); err != nil {
+				return err
+			}
+		}
+	default:
+		items = f.apply(items)
 
-				l.ErrorContext(
-					ctx,
-					"failed to exec insert statement",
-					"err", err,
-					"item", fmt.Sprintf("%+v", itm),
-				)
-				continue
+		if len(items) > 0 || !opts.skipEmptyOutput {
+			if err := renderOutput(ctx, l, items, out); err != nil {
+				return err
 			}
+		}
+	}
 
-			newItems = append(newItems, itm)
+	// Only once the items are processed, a failed run must not cause the
+	// next one to skip them. The validators are kept if the page wasn't
+	// fetched.
+	if opts.httpCacheFile != "" && !fromCache {
+		if err := savePageValidators(opts.httpCacheFile, validators); err != nil {
+			return err
 		}
+	}
+	for _, src := range scraped {
+		opts.metrics[src.state].setSuccess(time.Now())
+	}
 
-		items = newItems
+	return srcErr
+}
+
+// sourceResult is what scrapeSource returns of a source.
+type sourceResult struct {
+	items []*item
+	// Of the fetched page, empty if the page wasn't fetched
+	validators pageValidators
+	// Whether the items are those of the result cache
+	fromCache bool
+}
+
+// scrapeSource returns the items of src, the cached ones if opts has a
+// result cache which hasn't expired yet.
+func scrapeSource(
+	ctx context.Context,
+	l *slog.Logger,
+	src *source,
+	load loadOptions,
+	opts runOptions,
+) (sourceResult, error) {
+	load.metrics = opts.metrics[src.state]
+
+	var cacheKey string
+	if opts.resultCacheFile != "" {
+		cacheKey = resultCacheKey(src, load)
+		items, err := loadScrapeResult(opts.resultCacheFile, cacheKey, opts.resultCacheTTL, time.Now())
+		if err != nil {
+			// Not fatal, scrape instead
+			l.WarnContext(ctx, err.Error())
+		} else if items != nil {
+			l.InfoContext(ctx, "using cached items, see -no-cache", "items", len(items))
+			return sourceResult{items: items, fromCache: true}, nil
+		}
 	}
 
-	if printAsJSON {
-		// Print as JSON
-		enc := json.NewEncoder(os.Stdout)
-		for _, itm := range items {
-			if err := enc.Encode(itm); err != nil {
-				return fmt.Errorf("failed to JSON-print to stdout: %w", err)
-			}
+	scrapedAt := time.Now()
+	items, validators, err := func() ([]*item, pageValidators, error) {
+		// Only bound the scrape, rendering may take longer (e.g. geocoding)
+		ctx, cancel := context.WithTimeout(ctx, load.scrapeTimeout)
+		defer cancel()
+
+		return loadItems(ctx, l, src, load)
+	}()
+	if err != nil {
+		return sourceResult{}, err
+	}
+	if opts.resultCacheFile != "" {
+		if err := saveScrapeResult(opts.resultCacheFile, scrapeResult{
+			Key:       cacheKey,
+			ScrapedAt: scrapedAt,
+			Items:     items,
+		}); err != nil {
+			l.WarnContext(ctx, err.Error())
 		}
-	} else {
-		// Print as table
-		t := table.NewWriter()
-		t.SetAutoIndex(true)
-		t.SetTitle("Lebensmittelkontrolle")
-		header := table.Row{
-			"Behörde",
-			"Datum Veröffentlichung",
-			"Feststellungstag",
-			"Betriebsbezeichnung",
-			"Anschrift",
-		}
-		if tableShowDetails {
-			for _, h := range []string{
-				"Sachverhalt/Grund der Beanstandung",
-				"Rechtsgrundlage",
-				"Hinweise zur Mängelbeseitigung und Bemerkungen",
-			} {
-				header = append(header, h)
-			}
+	}
+
+	return sourceResult{items: items, validators: validators}, nil
+}
+
+// scrapeSources scrapes the official pages of the sources concurrently.
+// A failing source doesn't fail the others, it returns the items of the
+// sources scraped along with those sources and the errors of the others
+// joined.
+func scrapeSources(
+	ctx context.Context,
+	l *slog.Logger,
+	srcs []*source,
+	load loadOptions,
+	opts runOptions,
+) ([]*item, []*source, error) {
+	var (
+		items = make([][]*item, len(srcs))
+		errs  = make([]error, len(srcs))
+		wg    sync.WaitGroup
+	)
+	for i, src := range srcs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			srcLoad := load
+			srcLoad.url = src.url
+			var res sourceResult
+			res, errs[i] = scrapeSource(ctx, l, src, srcLoad, opts)
+			items[i] = res.items
+		}()
+	}
+	wg.Wait()
+
+	var (
+		all     []*item
+		scraped []*source
+	)
+	for i, src := range srcs {
+		if errs[i] != nil {
+			l.WarnContext(ctx, "failed to scrape source", "state", src.state, "err", errs[i])
+			errs[i] = fmt.Errorf("failed to scrape source %q: %w", src.state, errs[i])
+			continue
 		}
-		t.AppendHeader(header)
-		for _, itm := range items {
-			row := table.Row{
-				capstring(itm.Authority, tableMaxWidth),
-				capstring(itm.PublishedAtStr, tableMaxWidth),
-				capstring(itm.FoundAtStr, tableMaxWidth),
-				capstring(itm.Name, tableMaxWidth),
-				capstring(itm.Address, tableMaxWidth),
-			}
-			if tableShowDetails {
-				for _, r := range []string{
-					capstring(itm.Reason, tableMaxWidth),
-					capstring(itm.LegalBasis, tableMaxWidth),
-					capstring(itm.Info, tableMaxWidth),
-				} {
-					row = append(row, r)
-				}
+		l.InfoContext(ctx, "scraped source", "state", src.state, "items", len(items[i]))
+		all = append(all, items[i]...)
+		scraped = append(scraped, src)
+	}
+
+	return all, scraped, errors.Join(errs...)
+}
+
+// runWatch calls scrape every interval until ctx is done. A failed
+// scrape is logged and retried with the next one.
+func runWatch(ctx context.Context, l *slog.Logger, interval time.Duration, scrape func() error) error {
+	for {
+		if err := scrape(); err != nil {
+			if ctx.Err() != nil {
+				break
 			}
-			t.AppendRow(row)
+			l.ErrorContext(ctx, err.Error())
 		}
 
-		//nolint:forbidigo // We explicitly want to print to stdout
-		if _, err := fmt.Println(t.Render()); err != nil {
-			return fmt.Errorf("failed to print to stdout: %w", err)
+		l.DebugContext(ctx, "waiting for next scrape", "next", time.Now().Add(interval).Format(timestampFormat))
+		if err := waitContext(ctx, interval); err != nil {
+			break
 		}
 	}
 
+	l.InfoContext(ctx, "stopped watching")
+
+	return nil
+}
+
+// stringsFlag is a flag which may be repeated to collect several values.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
 	return nil
 }
 
+// runQuery prints the stored items which match f, or the history of
+// their businesses if history is set.
+func runQuery(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	f *filter,
+	history bool,
+	out outputOptions,
+) error {
+	st, err := openExistingStorage(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	items, err := st.queryItems(ctx, l, f)
+	if err != nil {
+		return err
+	}
+
+	return renderItemsOrHistory(ctx, l, st, items, history, out)
+}
+
+// runSearch prints the stored items containing all terms which match f,
+// or the history of their businesses if history is set.
+func runSearch(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	terms []string,
+	f *filter,
+	history bool,
+	out outputOptions,
+) error {
+	st, err := openExistingStorage(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	items, err := st.searchItems(ctx, l, terms)
+	if err != nil {
+		return err
+	}
+
+	return renderItemsOrHistory(ctx, l, st, f.apply(items), history, out)
+}
+
+// renderItemsOrHistory renders the items, or the history of their
+// businesses if history is set.
+func renderItemsOrHistory(
+	ctx context.Context,
+	l *slog.Logger,
+	st storage,
+	items []*item,
+	history bool,
+	out outputOptions,
+) error {
+	if !history {
+		return renderOutput(ctx, l, items, out)
+	}
+
+	entries, err := st.itemHistory(ctx, l, items)
+	if err != nil {
+		return err
+	}
+	return writeOutput(out.file, func(w io.Writer) error {
+		return renderHistory(w, entries, out)
+	})
+}
+
+// runCount prints the number of stored items matching f.
+func runCount(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	f *filter,
+	out outputOptions,
+) error {
+	st, err := openExistingStorage(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	n, err := st.countItems(ctx, l, f)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(out.file, func(w io.Writer) error {
+		if _, err := fmt.Fprintln(w, n); err != nil {
+			return fmt.Errorf("failed to print count: %w", err)
+		}
+		return nil
+	})
+}
+
+// runExport prints all stored items as JSON, newline-delimited or as a
+// single array.
+func runExport(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	out outputOptions,
+) error {
+	if out.format != outputFormatTable && out.format != outputFormatJSON && out.format != outputFormatJSONArray {
+		return errors.New("export only supports JSON output")
+	}
+
+	st, err := openExistingStorage(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	return writeOutput(out.file, func(w io.Writer) error {
+		jw := newJSONStreamWriter(w, out.format == outputFormatJSONArray, out.jsonIndent)
+		if err := st.eachItem(ctx, l, jw.write); err != nil {
+			return err
+		}
+		return jw.close()
+	})
+}
+
+// runImport stores the items of a JSON file as printed by export.
+func runImport(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	file string,
+) error {
+	if file == "" {
+		return errors.New("no file to import given")
+	}
+
+	fh, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer func() {
+		if err := fh.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close import file: %w", err).Error())
+		}
+	}()
+
+	items, err := decodeItems(fh)
+	if err != nil {
+		return err
+	}
+
+	st, err := openStorage(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	inserted, skipped, err := st.importItems(ctx, l, items)
+	if err != nil {
+		return err
+	}
+
+	l.InfoContext(
+		ctx,
+		"successfully imported items",
+		"inserted", inserted,
+		"skipped", skipped,
+	)
+
+	return nil
+}
+
+func runPrune(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	before time.Time,
+	soft bool,
+) error {
+	st, err := openExistingStorage(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	return st.pruneItems(ctx, l, before, soft)
+}
+
+// newLogger returns the logger of the given level writing to logTarget.
+func newLogger(level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(logTarget, &slog.HandlerOptions{
+		Level: level,
+	}))
+}
+
+// flagEnvVars maps the flags which have an environment variable
+// counterpart to it.
+func flagEnvVars() map[string]string {
+	return map[string]string{
+		"debug":   "DEBUG",
+		"timeout": "LMK_TIMEOUT",
+		"url":     "LMK_URL",
+	}
+}
+
 func main() {
+	watch := flag.Duration("watch", 0, "scrape every `interval`, e.g. 6h, until interrupted, combined with -new only new items are printed each time")
+	digestAt := flag.String("digest", "", "with -watch, email the new items as a single digest on `schedule`, e.g. daily@08:00 ("+digestLocation+"), instead of on every scrape, see SMTP_HOST")
+	metricsAddr := flag.String("metrics-addr", "", "serve Prometheus metrics of the scrapes at /metrics on `address`, e.g. localhost:9090, best combined with -watch")
+	statsTop := flag.Int("top", defaultStatsTop, "list the top `n` legal bases and businesses with the stats subcommand")
+	listenAddr := flag.String("listen", defaultListenAddr, "serve the stored items at `address` with the serve and grpc subcommands, the latter scraping like -new every -watch interval if set")
+	showVersion := flag.Bool("version", false, "print the version and exit, same as the version subcommand")
+	configFile := flag.String("config", "", "read settings from the YAML config `file`, keyed by flag or environment variable name, defaults to $LMK_CONFIG")
 	newOnly := flag.Bool("new", false, "new items only")
-	printAsJSON := flag.Bool("json", false, "print as JSON")
+	webhookURL := flag.String("webhook", "", "with -new, post the new items as a JSON array to `url`, signed with $LMK_WEBHOOK_SECRET if set, retried like fetching the page")
+	mqttQoS := flag.Int("mqtt-qos", 0, "with -new, publish the new items to $MQTT_BROKER with QoS `level`, one of 0, 1, 2")
+	mqttRetain := flag.Bool("mqtt-retain", false, "with -new, publish the new items to $MQTT_BROKER as retained messages")
+	pageFile := flag.String("file", "", "parse the page from the local HTML file at `path` instead of fetching it, - reads it from stdin")
+	sourceStateList := flag.String("source", defaultSourceState, "scrape the pages of the Bundesländer in the comma-separated `list` concurrently, of "+strings.Join(sourceStates(), ", ")+" and those of the sources key of the config, mapping states to the URLs of pages laid out like "+defaultSourceState+"'s")
+	sourceURL := flag.String("url", "", "fetch the items from `url` instead of the source's official page, defaults to $LMK_URL")
+	fetchAttempts := flag.Int("attempts", defaultFetchAttempts, "fetch the page up to `n` times, retrying on network errors, timeouts and server errors")
+	fetchInterval := flag.Duration("fetch-interval", defaultFetchInterval, "wait at least `duration` between requests to the source's server, e.g. when following pagination or retrying, not counting towards -timeout, 0 doesn't wait, the conservative default is polite to the authorities' servers")
+	timeout := flag.Duration("timeout", 0, "fail HTTP requests taking longer than `duration`, each attempt, see -attempts, gets its own, the whole scrape is bounded by -scrape-timeout, defaults to $LMK_TIMEOUT or "+defaultRequestTimeout.String())
+	scrapeTimeout := flag.Duration("scrape-timeout", defaultScrapeTimeout, "fail scraping a source taking longer than `duration`, fetching all of its pages along with the retries and -fetch-interval waits")
+	proxyURL := flag.String("proxy", "", "fetch the page through the proxy at `url` instead of the one set by $HTTP_PROXY and $HTTPS_PROXY, hosts in $NO_PROXY are still fetched directly")
+	noCache := flag.Bool("no-cache", false, "always scrape the page, instead of reusing the items scraped by a run within -cache-ttl, cached in "+resultCacheFileName+" next to $SQLITE_FILE")
+	cacheTTL := flag.Duration("cache-ttl", defaultResultCacheTTL, "reuse the items scraped by a previous run for `duration`, unless -watch or -file is set, or the build, the page or the options of parsing it, e.g. -lenient, -labels-file or the authorities of the config, changed")
+	ifModified := flag.Bool("if-modified", false, "skip processing the page if it hasn't changed since the last run with -if-modified")
+	httpCacheFile := flag.String("http-cache", "", "cache the page validators used by -if-modified in `path`, defaults to "+httpCacheFileName+" next to $SQLITE_FILE")
+	history := flag.Bool("history", false, "with -new, -vanished or -diff record the changed fields of items of businesses, i.e. of the same name and address, stored before, with query and search print the recorded changes of the matching items instead")
+	geocode := flag.Bool("geocode", false, "geocode the addresses of the items, storing the coordinates along with them")
+	lenient := flag.Bool("lenient", false, "only warn if the labels of the table heading differ from the expected ones")
+	labelsFile := flag.String("labels-file", "", "read the expected labels of the table heading from `path`, one per line in column order")
+	snapshotDir := flag.String("snapshot-dir", "", fmt.Sprintf("save the fetched page to a timestamped file in `dir`, keeping the latest %d snapshots", snapshotsToKeep))
+	vanished := flag.Bool("vanished", false, "stored items which are no longer published, combined with -new the new items as well")
+	diff := flag.Bool("diff", false, "new, reappeared and vanished items since the previous scrape, in sections")
+	printAsJSON := flag.Bool("json", false, "print as newline-delimited JSON, one object per line")
+	printAsJSONArray := flag.Bool("json-array", false, "print as a single JSON array")
+	jsonIndent := flag.Bool("json-indent", false, "indent JSON output")
+	printSchema := flag.Bool("schema", false, "print the JSON Schema of the items printed by -json and -json-array, restricted to -columns if set, and exit")
+	printOpenAPI := flag.Bool("openapi", false, "print the OpenAPI document of the HTTP API of the serve subcommand, also served at /openapi.json, and exit")
+	english := flag.Bool("en", false, "label the columns of tables, CSV, TSV, Markdown, HTML and XLSX in English, the values stay German")
+	anonymize := flag.Bool("anonymize", false, "replace business names and addresses in the output by tokens, the same for the same business, see LMK_ANONYMIZE_SECRET")
+	tableColor := flag.Bool("color", false, "highlight recent items of tables in color, the default if writing to a terminal and NO_COLOR isn't set")
+	tableNoColor := flag.Bool("no-color", false, "never use color")
+	tableDetails := flag.Bool("details", true, "show the scraped columns in tables, Markdown and HTML if -columns isn't set, otherwise the authority, dates, name and address only")
+	tableMaxWidth := flag.Int("max-width", defaultTableMaxWidth, "truncate table cells to `n` characters, don't truncate if 0")
+	outFile := flag.String("out", "", "write output to `path` instead of stdout")
+	columnsList := flag.String("columns", "", "comma-separated `list` of columns to output (table, Markdown, CSV, TSV, HTML, JSON, XLSX), the derived ones, e.g. city or first_seen, are only output if selected")
+	printAsCSV := flag.Bool("csv", false, "print as CSV")
+	printAsTSV := flag.Bool("tsv", false, "print as TSV")
+	printAsMarkdown := flag.Bool("markdown", false, "print as Markdown table")
+	printAsHTML := flag.Bool("html", false, "print as HTML document")
+	htmlQRCodes := flag.Bool("html-qr", false, "add a QR code of the map link of each item to HTML output, e.g. to print it")
+	printAsYAML := flag.Bool("yaml", false, "print as YAML")
+	printAsRSS := flag.Bool("rss", false, "print as RSS 2.0 feed")
+	printAsAtom := flag.Bool("atom", false, "print as Atom 1.0 feed")
+	printAsICal := flag.Bool("ical", false, "print as iCalendar of the inspection dates")
+	printAsGeoJSON := flag.Bool("geojson", false, "print as GeoJSON with geocoded addresses")
+	printAsSQLDump := flag.Bool("sqldump", false, "print as SQL statements")
+	xlsxFile := flag.String("xlsx", "", "write an Excel workbook to `path`")
+
+	publishedAfter := flag.String("published-after", "", "only items published on or after `date` (DD.MM.YYYY)")
+	publishedBefore := flag.String("published-before", "", "only items published on or before `date` (DD.MM.YYYY)")
+	sinceDays := flag.Int("since-days", 0, "only items published within the last `n` days, i.e. on or after today minus n days")
+	foundAfter := flag.String("found-after", "", "only items inspected on or after `date` (DD.MM.YYYY), uses the first inspection date only")
+	foundBefore := flag.String("found-before", "", "only items inspected on or before `date` (DD.MM.YYYY), uses the first inspection date only")
+
+	authority := flag.String("authority", "", "only items whose authority contains `substring`, case-insensitive")
+	var reasonKeywords stringsFlag
+	flag.Var(&reasonKeywords, "reason", "only items whose reason contains `keyword`, case-insensitive, may be repeated to match any of them")
+	legalBasis := flag.String("legal-basis", "", "only items whose legal basis contains `substring`, case-insensitive, items without a legal basis never match")
+	city := flag.String("city", "", "only items whose address contains `substring`, case-insensitive")
+	postalCodePrefix := flag.String("plz", "", "only items whose postal code starts with `prefix`")
+	var states stringsFlag
+	flag.Var(&states, "state", "only items of the Bundesland `state`, e.g. bw, may be repeated to match any of them")
+	nameRegex := flag.String("name-regex", "", "only items whose business name matches `regexp`, case-insensitive unless it starts with its own flags")
+	dedup := flag.Bool("dedup", false, "collapse items with the same business name, address and reason, ignoring case and whitespace, into the one published first")
+	includeDeleted := flag.Bool("include-deleted", false, "include stored items soft-deleted by -prune-before with -soft-delete")
+
+	sortField := flag.String("sort", sortByPublished, "sort items by `field`, one of authority, name, published, found")
+	sortDesc := flag.Bool("desc", false, "sort in descending order, e.g. latest first")
+	offset := flag.Int("offset", 0, "skip the first `n` items, applied after filtering and sorting but before -limit")
+	limit := flag.Int("limit", 0, "print at most `n` items, no limit if 0 or negative")
+
+	pruneBefore := flag.String("prune-before", "", "delete stored items published before `date` (DD.MM.YYYY) and compact the database, items without a valid publication date are kept")
+	softDelete := flag.Bool("soft-delete", false, "with -prune-before mark the items as deleted rather than deleting them, they are excluded unless -include-deleted is set but keep occupying space until pruned without -soft-delete")
+
+	memory := flag.Bool("memory", false, "store the items in an in-memory database instead of $SQLITE_FILE, like setting it to "+sqliteMemoryFile+", they're lost on exit, e.g. to -watch with -new without touching the disk")
 
 	debug := flag.Bool("debug", false, "enable debug mode")
 
 	flag.Parse()
 
-	sqliteFile := getenv("SQLITE_FILE", defaultSQLiteFilePath)
+	// Only logs the errors of parsing the arguments and loading the
+	// config, which configures the logger
+	bl := newLogger(slog.LevelInfo)
 
-	ll := new(slog.LevelVar)
-	ll.Set(slog.LevelInfo)
-	l := slog.New(slog.NewJSONHandler(logTarget, &slog.HandlerOptions{
-		Level: ll,
-	}))
-	slog.SetDefault(l)
+	// Flags may be given before as well as after the subcommand and its
+	// arguments
+	command := flag.Arg(0)
+	if command != "" {
+		if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+			bl.Error(err.Error())
+			return
+		}
+	}
+	// Of the import, backup and restore commands
+	var commandFile string
+	if (command == "import" || command == "backup" || command == "restore") && flag.NArg() > 0 {
+		commandFile = flag.Arg(0)
+		if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+			bl.Error(err.Error())
+			return
+		}
+	}
+	var searchTerms []string
+	for command == "search" && flag.NArg() > 0 {
+		if t := trimText(flag.Arg(0)); t != "" {
+			searchTerms = append(searchTerms, t)
+		}
+		if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+			bl.Error(err.Error())
+			return
+		}
+	}
+
+	// Before anything which may fail, e.g. loading the config
+	if *showVersion || command == "version" {
+		if _, err := fmt.Fprintln(os.Stdout, buildVersion()); err != nil {
+			bl.Error(fmt.Errorf("failed to print version: %w", err).Error())
+		}
+		return
+	}
+
+	// Flags take precedence over the environment, which takes precedence
+	// over the config
+	configPath := *configFile
+	if configPath == "" {
+		configPath = getenv("LMK_CONFIG", "")
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		bl.Error(err.Error())
+		return
+	}
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	if err := cfg.applyFlags(
+		func(name string) bool { return flag.Lookup(name) != nil },
+		func(name string) bool { return setFlags[name] || os.Getenv(flagEnvVars()[name]) != "" },
+		flag.Set,
+	); err != nil {
+		bl.Error(err.Error())
+		return
+	}
 
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.getenv("LOG_LEVEL", slog.LevelInfo.String()))); err != nil {
+		bl.Error(fmt.Errorf("failed to parse log level: %w", err).Error())
+		return
+	}
 	// We have a debug env var as well as a debug CLI flag
-	if getenv("DEBUG", "false") == "true" {
+	if cfg.getenv("DEBUG", "false") == "true" {
 		*debug = true
 	}
-
 	if *debug {
-		ll.Set(slog.LevelDebug)
+		level = slog.LevelDebug
 	}
+	l := newLogger(level)
+	slog.SetDefault(l)
 
-	if err := run(
-		context.Background(),
-		l,
-		sqliteFile,
-		*newOnly,
-		*printAsJSON,
-	); err != nil {
+	storageCfg := storageConfig{
+		sqlite: sqliteConfig{
+			file: cfg.getenv("SQLITE_FILE", defaultSQLiteFilePath),
+		},
+		databaseURL: cfg.getenv("DATABASE_URL", ""),
+	}
+	sqliteJournalMode := cfg.getenv("SQLITE_JOURNAL_MODE", defaultSQLiteJournalMode)
+	sqliteBusyTimeout := cfg.getenv("SQLITE_BUSY_TIMEOUT", defaultSQLiteBusyTimeout.String())
+	userAgent := cfg.getenv("LMK_USER_AGENT", defaultUserAgent)
+	geocoderURL := cfg.getenv("GEOCODER_URL", defaultGeocoderURL)
+	geocodeCacheFile := cfg.getenv("GEOCODE_CACHE_FILE", defaultGeocodeCacheFilePath)
+	geocodeInterval := cfg.getenv("GEOCODE_INTERVAL", nominatimMinRequestInterval.String())
+	geocodeConcurrency := cfg.getenv("GEOCODE_CONCURRENCY", strconv.Itoa(defaultGeocodeConcurrency))
+
+	journalMode, err := parseSQLiteJournalMode(sqliteJournalMode)
+	if err != nil {
+		l.Error(err.Error())
+		return
+	}
+	storageCfg.sqlite.journalMode = journalMode
+	busyTimeout, err := time.ParseDuration(sqliteBusyTimeout)
+	if err != nil {
+		l.Error(fmt.Errorf("failed to parse sqlite busy timeout: %w", err).Error())
+		return
+	}
+	storageCfg.sqlite.busyTimeout = busyTimeout
+	if *memory {
+		if storageCfg.databaseURL != "" {
+			l.Error("-memory can't be combined with DATABASE_URL")
+			return
+		}
+		storageCfg.sqlite.file = sqliteMemoryFile
+	}
+	// Postgres and in-memory databases don't use the sqlite file
+	if storageCfg.databaseURL == "" && !storageCfg.sqlite.inMemory() && cfg.getenv("SQLITE_KEY", "") != "" {
+		// Rather than storing the items unencrypted, which may be
+		// personal data
+		l.Error("SQLITE_KEY is set but the sqlite database can't be encrypted, put SQLITE_FILE on an encrypted filesystem or use DATABASE_URL instead, see the README")
+		return
+	}
+	requestTimeout := *timeout
+	if requestTimeout == 0 {
+		d, err := time.ParseDuration(cfg.getenv("LMK_TIMEOUT", defaultRequestTimeout.String()))
+		if err != nil {
+			l.Error(fmt.Errorf("failed to parse request timeout: %w", err).Error())
+			return
+		}
+		requestTimeout = d
+	}
+	if requestTimeout <= 0 {
+		l.Error("request timeout must be positive")
+		return
+	}
+	geocoderInterval, err := time.ParseDuration(geocodeInterval)
+	if err != nil {
+		l.Error(fmt.Errorf("failed to parse geocode interval: %w", err).Error())
+		return
+	}
+	if geocoderInterval < 0 {
+		l.Error("GEOCODE_INTERVAL must not be negative")
+		return
+	}
+	geocoderConcurrency, err := strconv.Atoi(geocodeConcurrency)
+	if err != nil || geocoderConcurrency <= 0 {
+		l.Error("GEOCODE_CONCURRENCY must be a positive number")
+		return
+	}
+	if *scrapeTimeout <= 0 {
+		l.Error("-scrape-timeout must be positive")
+		return
+	}
+	if *fetchInterval < 0 {
+		l.Error("fetch interval must not be negative")
+		return
+	}
+	if *watch < 0 {
+		l.Error("watch interval must be positive")
+		return
+	}
+	// The grpc command scrapes to stream the new items
+	if *watch > 0 && command != "" && command != "grpc" {
+		l.Error("-watch only applies to scraping, not to the " + command + " command")
+		return
+	}
+	if *digestAt != "" && (*watch == 0 || command != "") {
+		l.Error("-digest requires -watch and scraping")
+		return
+	}
+	if *digestAt != "" && storageCfg.sqlite.inMemory() {
+		l.Error("-digest can't be combined with an in-memory database, the pending items are saved next to it")
+		return
+	}
+	if *softDelete && *pruneBefore == "" {
+		l.Error("-soft-delete requires -prune-before")
+		return
+	}
+	if *metricsAddr != "" && command != "" {
+		l.Error("-metrics-addr only applies to scraping, not to the " + command + " command")
+		return
+	}
+
+	f, err := newFilter(filterOptions{
+		publishedAfter:   *publishedAfter,
+		publishedBefore:  *publishedBefore,
+		sinceDays:        *sinceDays,
+		foundAfter:       *foundAfter,
+		foundBefore:      *foundBefore,
+		authority:        *authority,
+		reasonKeywords:   reasonKeywords,
+		legalBasis:       *legalBasis,
+		city:             *city,
+		postalCodePrefix: *postalCodePrefix,
+		states:           states,
+		nameRegex:        *nameRegex,
+		dedup:            *dedup,
+		includeDeleted:   *includeDeleted,
+		sortField:        *sortField,
+		sortDesc:         *sortDesc,
+		offset:           *offset,
+		limit:            *limit,
+	}, time.Now())
+	if err != nil {
+		l.Error(err.Error())
+		return
+	}
+
+	columns, err := parseColumns(*columnsList)
+	if err != nil {
+		l.Error(err.Error())
+		return
+	}
+
+	format := outputFormatTable
+	var numFormats int
+	for _, f := range []struct {
+		enabled bool
+		format  outputFormat
+	}{
+		{*printAsJSON, outputFormatJSON},
+		{*printAsJSONArray, outputFormatJSONArray},
+		{*printAsCSV, outputFormatCSV},
+		{*printAsTSV, outputFormatTSV},
+		{*printAsMarkdown, outputFormatMarkdown},
+		{*printAsHTML, outputFormatHTML},
+		{*printAsYAML, outputFormatYAML},
+		{*printAsRSS, outputFormatRSS},
+		{*printAsAtom, outputFormatAtom},
+		{*printAsICal, outputFormatICal},
+		{*printAsGeoJSON, outputFormatGeoJSON},
+		{*printAsSQLDump, outputFormatSQLDump},
+		{*xlsxFile != "", outputFormatXLSX},
+	} {
+		if f.enabled {
+			format = f.format
+			numFormats++
+		}
+	}
+	if numFormats > 1 {
+		l.Error("only one output format may be selected")
+		return
+	}
+	var anon *anonymizer
+	if *anonymize {
+		if format == outputFormatGeoJSON {
+			l.Error("-anonymize can't be combined with -geojson")
+			return
+		}
+		anon = &anonymizer{secret: cfg.getenv("LMK_ANONYMIZE_SECRET", "")}
+	}
+	if *tableColor && *tableNoColor {
+		l.Error("only one of -color and -no-color may be set")
+		return
+	}
+	// See https://no-color.org/
+	useColor := *tableColor ||
+		(!*tableNoColor && getenv("NO_COLOR", "") == "" && *outFile == "" && isTerminal(os.Stdout))
+	if *tableMaxWidth < 0 {
+		l.Error("-max-width must not be negative")
+		return
+	}
+
+	if flag.NArg() > 0 {
+		l.Error(fmt.Sprintf("unexpected arguments: %s", strings.Join(flag.Args(), " ")))
+		return
+	}
+
+	if *printSchema {
+		if err := writeOutput(*outFile, func(w io.Writer) error {
+			return renderJSONSchema(w, columns)
+		}); err != nil {
+			l.Error(err.Error())
+		}
+		return
+	}
+
+	if *printOpenAPI {
+		if err := writeOutput(*outFile, func(w io.Writer) error {
+			return renderOpenAPISpec(w, true)
+		}); err != nil {
+			l.Error(err.Error())
+		}
+		return
+	}
+
+	if err := registerSQLiteFunctions(); err != nil {
+		l.Error(err.Error())
+		return
+	}
+
+	// Stops the running command, e.g. waiting in watch mode
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	out := outputOptions{
+		format:     format,
+		file:       *outFile,
+		columns:    columns,
+		jsonIndent: *jsonIndent,
+
+		tableMaxWidth: *tableMaxWidth,
+		tableDetails:  *tableDetails,
+		tableColor:    useColor,
+		english:       *english,
+		anonymizer:    anon,
+		htmlQRCodes:   *htmlQRCodes,
+
+		geocoder: geocoderConfig{
+			url:       geocoderURL,
+			cacheFile: geocodeCacheFile,
+			userAgent: userAgent,
+			timeout:   requestTimeout,
+
+			interval:    geocoderInterval,
+			concurrency: geocoderConcurrency,
+		},
+
+		xlsxFile: *xlsxFile,
+	}
+
+	if *pageFile != "" && *sourceURL != "" {
+		l.Error("only one of -file and -url may be set")
+		return
+	}
+	srcs, err := lookupSources(*sourceStateList, cfg.sources)
+	if err != nil {
+		l.Error(err.Error())
+		return
+	}
+	// The page, its URL, labels and validators are of a single source
+	if len(srcs) > 1 && (*pageFile != "" || *sourceURL != "" || cfg.getenv("LMK_URL", "") != "" || *labelsFile != "" || *ifModified) {
+		l.Error("-file, -url, LMK_URL, -labels-file and -if-modified only apply to a single -source")
+		return
+	}
+	if *ifModified && *pageFile != "" {
+		l.Error("-if-modified requires fetching the page, it can't be combined with -file")
+		return
+	}
+	var ifModifiedCacheFile string
+	if *ifModified {
+		ifModifiedCacheFile = *httpCacheFile
+		if ifModifiedCacheFile == "" && storageCfg.sqlite.inMemory() {
+			l.Error("-if-modified with an in-memory database requires -http-cache")
+			return
+		}
+		if ifModifiedCacheFile == "" {
+			ifModifiedCacheFile = filepath.Join(filepath.Dir(storageCfg.sqlite.file), httpCacheFileName)
+		}
+	}
+
+	if *cacheTTL <= 0 {
+		l.Error("-cache-ttl must be positive")
+		return
+	}
+	var resultCacheFile string
+	if !*noCache && *watch == 0 && *pageFile == "" && len(srcs) == 1 && !storageCfg.sqlite.inMemory() {
+		resultCacheFile = filepath.Join(filepath.Dir(storageCfg.sqlite.file), resultCacheFileName)
+	}
+
+	if len(cfg.authorities) > 0 {
+		n := newAuthorityNormalizer(cfg.authorities)
+		for i, src := range srcs {
+			srcs[i] = src.withAuthorities(n)
+		}
+	}
+	src := srcs[0]
+	if *labelsFile != "" {
+		labels, err := readLabels(*labelsFile)
+		if err != nil {
+			l.Error(err.Error())
+			return
+		}
+		if src, err = src.withLabels(labels); err != nil {
+			l.Error(err.Error())
+			return
+		}
+		srcs[0] = src
+	}
+	loadURL := *sourceURL
+	if loadURL == "" {
+		loadURL = cfg.getenv("LMK_URL", src.url)
+	}
+	if err := validateHTTPURL("source", loadURL); err != nil {
+		l.Error(err.Error())
+		return
+	}
+
+	var proxy *url.URL
+	if *proxyURL != "" {
+		u, err := parseProxyURL(*proxyURL)
+		if err != nil {
+			l.Error(err.Error())
+			return
+		}
+		proxy = u
+	}
+
+	// Used with -new and -diff only, see runOptions
+	notifyClient := &http.Client{
+		Transport: newProxyTransport(proxy),
+		Timeout:   requestTimeout,
+	}
+	// Links the page of the first source only
+	notifiers, err := newNotifiers(l, cfg, notifyClient, src.url, requestTimeout)
+	if err != nil {
+		l.Error(err.Error())
+		return
+	}
+	defer func() {
+		// Still flushes pending notifications if interrupted
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requestTimeout)
+		defer cancel()
+		closeNotifiers(closeCtx, l, notifiers)
+	}()
+	if *webhookURL != "" {
+		if err := validateHTTPURL("webhook", *webhookURL); err != nil {
+			l.Error(err.Error())
+			return
+		}
+		notifiers = append(notifiers, newWebhookNotifier(
+			l,
+			notifyClient,
+			*webhookURL,
+			userAgent,
+			cfg.getenv("LMK_WEBHOOK_SECRET", ""),
+			max(*fetchAttempts, 1),
+		))
+	}
+
+	if broker := cfg.getenv("MQTT_BROKER", ""); broker != "" {
+		if *mqttQoS < 0 || *mqttQoS > 2 {
+			l.Error("-mqtt-qos must be 0, 1 or 2")
+			return
+		}
+		notifiers = append(notifiers, newMQTTNotifier(
+			l,
+			broker,
+			cfg.getenv("MQTT_CLIENT_ID", ""),
+			cfg.getenv("MQTT_USERNAME", ""),
+			cfg.getenv("MQTT_PASSWORD", ""),
+			cfg.getenv("MQTT_TOPIC", defaultMQTTTopic),
+			byte(*mqttQoS),
+			*mqttRetain,
+			requestTimeout,
+		))
+	}
+
+	// Replaces the SMTP notifier, which sends the digests instead
+	var digest *digestNotifier
+	if *digestAt != "" {
+		if !*newOnly && !*diff {
+			l.Error("-digest requires -new or -diff")
+			return
+		}
+		schedule, err := parseDigestSchedule(*digestAt)
+		if err != nil {
+			l.Error(err.Error())
+			return
+		}
+		i := slices.IndexFunc(notifiers, func(n notifier) bool {
+			_, ok := n.(*smtpNotifier)
+			return ok
+		})
+		if i < 0 {
+			l.Error("-digest requires SMTP_HOST to be set")
+			return
+		}
+		digestFile := filepath.Join(filepath.Dir(storageCfg.sqlite.file), digestFileName)
+		if digest, err = newDigestNotifier(l, notifiers[i], digestFile, schedule); err != nil {
+			l.Error(err.Error())
+			return
+		}
+		notifiers[i] = digest
+	}
+
+	load := loadOptions{
+		file:          *pageFile,
+		url:           loadURL,
+		userAgent:     userAgent,
+		timeout:       requestTimeout,
+		scrapeTimeout: *scrapeTimeout,
+		attempts:      max(*fetchAttempts, 1),
+		snapshotDir:   *snapshotDir,
+		proxy:         proxy,
+		lenient:       *lenient,
+		limiter:       newFetchLimiter(*fetchInterval),
+	}
+
+	if *history && command != "" && command != "query" && command != "search" {
+		l.Error("-history only applies to scraping and the query and search commands")
+		return
+	}
+
+	var cmd func() error
+	switch command {
+	case "":
+		if *history && !*newOnly && !*vanished && !*diff {
+			l.Error("-history requires -new, -vanished or -diff")
+			return
+		}
+		if *diff && (*newOnly || *vanished) {
+			l.Error("-diff can't be combined with -new or -vanished")
+			return
+		}
+		if *diff && *xlsxFile != "" {
+			l.Error("-diff can't be combined with -xlsx")
+			return
+		}
+		var metrics map[string]*scrapeMetrics
+		if *metricsAddr != "" {
+			metrics = make(map[string]*scrapeMetrics, len(srcs))
+			for _, src := range srcs {
+				if metrics[src.state], err = newScrapeMetrics(prometheus.DefaultRegisterer, src.state); err != nil {
+					l.Error(err.Error())
+					return
+				}
+			}
+		}
+		cmd = func() error {
+			return run(ctx, l, storageCfg, srcs, load, runOptions{
+				newOnly:         *newOnly,
+				vanished:        *vanished,
+				diff:            *diff,
+				httpCacheFile:   ifModifiedCacheFile,
+				resultCacheFile: resultCacheFile,
+				resultCacheTTL:  *cacheTTL,
+				geocode:         *geocode,
+				history:         *history,
+				skipEmptyOutput: *watch > 0,
+				metrics:         metrics,
+				notifiers:       notifiers,
+			}, f, out)
+		}
+		if *watch > 0 {
+			once := cmd
+			cmd = func() error { return runWatch(ctx, l, *watch, once) }
+		}
+		if digest != nil {
+			watching := cmd
+			cmd = func() error { return withDigest(ctx, digest, watching) }
+		}
+		if *metricsAddr != "" {
+			ln, err := listen(ctx, *metricsAddr)
+			if err != nil {
+				l.Error(err.Error())
+				return
+			}
+			scrape := cmd
+			cmd = func() error { return withMetricsServer(ctx, l, ln, scrape) }
+		}
+	case "query":
+		cmd = func() error { return runQuery(ctx, l, storageCfg, f, *history, out) }
+	case "search":
+		if len(searchTerms) == 0 {
+			l.Error("search requires at least one term")
+			return
+		}
+		cmd = func() error { return runSearch(ctx, l, storageCfg, searchTerms, f, *history, out) }
+	case "count":
+		cmd = func() error { return runCount(ctx, l, storageCfg, f, out) }
+	case "stats":
+		if *statsTop <= 0 {
+			l.Error("-top must be positive")
+			return
+		}
+		cmd = func() error { return runStats(ctx, l, storageCfg, *statsTop, out) }
+	case "serve":
+		cmd = func() error { return runServe(ctx, l, storageCfg, *listenAddr) }
+	case "grpc":
+		var (
+			streams *itemStreams
+			scrape  func(ctx context.Context) error
+		)
+		if *watch > 0 {
+			streams = newItemStreams()
+			scrape = func(ctx context.Context) error {
+				return runWatch(ctx, l, *watch, func() error {
+					return run(ctx, l, storageCfg, srcs, load, runOptions{
+						newOnly:       true,
+						httpCacheFile: ifModifiedCacheFile,
+						geocode:       *geocode,
+						discardOutput: true,
+						notifiers:     append(slices.Clip(notifiers), streams),
+					}, f, out)
+				})
+			}
+		}
+		cmd = func() error { return runGRPC(ctx, l, storageCfg, *listenAddr, streams, scrape) }
+	case "export":
+		cmd = func() error { return runExport(ctx, l, storageCfg, out) }
+	case "import":
+		cmd = func() error { return runImport(ctx, l, storageCfg, commandFile) }
+	case "backup":
+		cmd = func() error { return runBackup(ctx, l, storageCfg, commandFile) }
+	case "restore":
+		cmd = func() error { return runRestore(ctx, l, storageCfg, commandFile) }
+	default:
+		l.Error(fmt.Sprintf("unknown command %q", command))
+		return
+	}
+
+	if storageCfg.databaseURL == "" && storageCfg.sqlite.inMemory() {
+		// The in-memory database is gone once its last connection is
+		// closed, keep one open so the runs share it, e.g. of -watch
+		st, err := openSQLite(ctx, l, storageCfg.sqlite)
+		if err != nil {
+			l.Error(err.Error())
+			return
+		}
+		defer st.close(ctx, l)
+	}
+
+	if *pruneBefore != "" {
+		before, err := parseDate(*pruneBefore)
+		if err != nil {
+			l.Error(err.Error())
+			return
+		}
+		if err := runPrune(ctx, l, storageCfg, before, *softDelete); err != nil {
+			l.Error(err.Error())
+			return
+		}
+	}
+
+	if err := cmd(); err != nil {
 		l.Error(err.Error())
 	}
 }
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestMain(m *testing.M) {
+	// Like main, before any database is opened
+	if err := registerSQLiteFunctions(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestCapstring(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		s    string
+		l    int
+		want string
+	}{
+		{"Müller-Thurgaustraße", 30, "Müller-Thurgaustraße"},
+		{"Müller-Thurgaustraße", 20, "Müller-Thurgaustraße"},
+		{"Müller-Thurgaustraße", 19, "Müller-Thurgaustraß…"},
+		{"Müller-Thurgaustraße", 2, "Mü…"},
+		{"Müller-Thurgaustraße", 1, "M…"},
+		{"Müller-Thurgaustraße", 0, "…"},
+		{"Metzgerei", 9, "Metzgerei"},
+		{"Metzgerei", 5, "Metzg…"},
+		{"", 5, ""},
+	} {
+		if got := capstring(tc.s, tc.l); got != tc.want {
+			t.Errorf("capstring(%q, %d) = %q, want %q", tc.s, tc.l, got, tc.want)
+		}
+	}
+}
+
+func TestItemMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	full := populatedItem()
+	// With all times known, it's encoded like without MarshalJSON, which
+	// catches jsonItem lacking fields of item or having them reordered
+	type plainItem item
+	want, err := json.Marshal((*plainItem)(full))
+	if err != nil {
+		t.Fatalf("failed to JSON-encode item: %v", err)
+	}
+	got, err := json.Marshal(full)
+	if err != nil {
+		t.Fatalf("failed to JSON-encode item: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	unknown := &item{
+		Authority: "Stadt Stuttgart",
+		Name:      "Metzgerei Schmid",
+		State:     "bw",
+	}
+	b, err := json.Marshal(unknown)
+	if err != nil {
+		t.Fatalf("failed to JSON-encode item: %v", err)
+	}
+	var keys map[string]any
+	if err := json.Unmarshal(b, &keys); err != nil {
+		t.Fatalf("failed to JSON-decode item: %v", err)
+	}
+	for _, k := range []string{
+		"published_at",
+		"found_at",
+		"published_at_end",
+		"found_at_end",
+		"first_seen",
+		"last_seen",
+		"deleted_at",
+	} {
+		if v, ok := keys[k]; ok {
+			t.Errorf("got %s %v of an unknown time, want it omitted", k, v)
+		}
+	}
+
+	// Both decode to the items again
+	for _, itm := range []*item{full, unknown} {
+		b, err := json.Marshal(itm)
+		if err != nil {
+			t.Fatalf("failed to JSON-encode item: %v", err)
+		}
+		var decoded item
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatalf("failed to JSON-decode item: %v", err)
+		}
+		if decoded != *itm {
+			t.Errorf("got %+v decoding %s, want %+v", decoded, b, *itm)
+		}
+	}
+}
+
+func TestRunSeveralSources(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+	dir := t.TempDir()
+
+	layout, err := lookupSource(defaultSourceState)
+	if err != nil {
+		t.Fatalf("failed to look up source: %v", err)
+	}
+	// The same business is listed by both states
+	row := []string{
+		"Stadt Ulm",
+		"01.03.2024",
+		"Café Ulm",
+		"Münsterplatz 1, 89073 Ulm",
+		"20.02.2024",
+		"Schimmel",
+		"§ 40 Abs. 1a LFGB",
+		"",
+	}
+	page := sourcePage(layout, [][]string{row}, "")
+	serve := func(code int) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(code)
+			if _, err := io.WriteString(w, page); err != nil {
+				t.Errorf("failed to write page: %v", err)
+			}
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	bw, by, he := serve(http.StatusOK), serve(http.StatusOK), serve(http.StatusInternalServerError)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configFile, []byte(fmt.Sprintf(
+		"sources:\n  bw: %s\n  by: %s\n  he: %s\n",
+		bw.URL, by.URL, he.URL,
+	)), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	srcs, err := lookupSources("bw,by,he", cfg.sources)
+	if err != nil {
+		t.Fatalf("failed to look up sources: %v", err)
+	}
+
+	f, err := newFilter(filterOptions{sortField: sortByPublished}, time.Now())
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+	outFile := filepath.Join(dir, "out.json")
+	err = run(
+		ctx,
+		l,
+		storageConfig{sqlite: sqliteConfig{file: filepath.Join(dir, "db.sqlite")}},
+		srcs,
+		loadOptions{
+			timeout:       5 * time.Second,
+			scrapeTimeout: 10 * time.Second,
+			attempts:      1,
+		},
+		runOptions{newOnly: true},
+		f,
+		outputOptions{format: outputFormatJSON, file: outFile},
+	)
+
+	// Only the failing source fails, once the items of the others are
+	// processed
+	var se *statusError
+	if !errors.As(err, &se) || se.code != http.StatusInternalServerError {
+		t.Fatalf("got error %v, want the server error of he", err)
+	}
+	if !strings.Contains(err.Error(), `"he"`) || strings.Contains(err.Error(), `"bw"`) || strings.Contains(err.Error(), `"by"`) {
+		t.Errorf("got error %q, want the one of he only", err)
+	}
+
+	out, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	var (
+		states []string
+		hashes []string
+	)
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var itm item
+		if err := dec.Decode(&itm); err != nil {
+			t.Fatalf("failed to JSON-decode output: %v", err)
+		}
+		if itm.Name != "Café Ulm" {
+			t.Errorf("got item %q, want the one of the pages", itm.Name)
+		}
+		states = append(states, itm.State)
+		hashes = append(hashes, itemHash(&itm))
+	}
+	// Both are new, the state separates their hashes
+	slices.Sort(states)
+	if want := []string{"bw", "by"}; !slices.Equal(states, want) {
+		t.Errorf("got new items of states %q, want %q", states, want)
+	}
+	if len(hashes) == 2 && hashes[0] == hashes[1] { //nolint:mnd // See above
+		t.Errorf("got the same hash %s of the items of both states", hashes[0])
+	}
+}
+
+// recordingNotifier records the items it's notified about.
+type recordingNotifier struct {
+	items []*item
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(_ context.Context, items []*item) error {
+	n.items = append(n.items, items...)
+	return nil
+}
+
+func TestRunNotifiesBeyondLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+	dir := t.TempDir()
+
+	src, err := lookupSource("bw")
+	if err != nil {
+		t.Fatalf("failed to look up source: %v", err)
+	}
+	row := func(authority, name string) []string {
+		return []string{authority, "01.03.2024", name, "Königstraße 10, 70173 Stuttgart", "20.02.2024", "Schimmel", "§ 11 LFGB", ""}
+	}
+	pageFile := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(pageFile, []byte(sourcePage(src, [][]string{
+		row("Stadt Stuttgart", "Café A"),
+		row("Stadt Ulm", "Café B"),
+		row("Stadt Stuttgart", "Café C"),
+	}, "")), 0o600); err != nil {
+		t.Fatalf("failed to write page: %v", err)
+	}
+
+	// Pages the output, but mustn't page the notifications
+	f, err := newFilter(filterOptions{
+		authority: "stuttgart",
+		sortField: sortByName,
+		sortDesc:  true,
+		limit:     1,
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("failed to create filter: %v", err)
+	}
+	n := &recordingNotifier{}
+	outFile := filepath.Join(dir, "out.json")
+	if err := run(
+		ctx,
+		l,
+		storageConfig{sqlite: sqliteConfig{file: filepath.Join(dir, "db.sqlite")}},
+		[]*source{src},
+		loadOptions{file: pageFile, scrapeTimeout: 10 * time.Second},
+		runOptions{newOnly: true, notifiers: []notifier{n}},
+		f,
+		outputOptions{format: outputFormatJSON, file: outFile},
+	); err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+
+	if got := itemNames(n.items); !slices.Equal(got, []string{"Café A", "Café C"}) {
+		t.Errorf("got notified about %q, want all new items of the authority", got)
+	}
+	out, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if got := bytes.Count(out, []byte("\n")); got != 1 {
+		t.Errorf("got %d items output, want the limit of 1", got)
+	}
+}
+
+// BenchmarkParse parses the page of 500 items in testdata, laid out like
+// Baden-Württemberg's. The rows are parsed concurrently, compare e.g.
+// -cpu 1,4.
+func BenchmarkParse(b *testing.B) {
+	ctx := context.Background()
+	l := testLogger()
+
+	src, err := lookupSource("bw")
+	if err != nil {
+		b.Fatalf("failed to look up source: %v", err)
+	}
+	page, err := os.ReadFile("testdata/bw.html")
+	if err != nil {
+		b.Fatalf("failed to read page: %v", err)
+	}
+
+	b.SetBytes(int64(len(page)))
+	b.ResetTimer()
+	for range b.N {
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+		if err != nil {
+			b.Fatalf("failed to create document: %v", err)
+		}
+		items, err := parseItems(ctx, l, src, doc, false)
+		if err != nil {
+			b.Fatalf("failed to parse page: %v", err)
+		}
+		if len(items) != 500 { //nolint:mnd // Of the page
+			b.Fatalf("got %d items, want 500", len(items))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrapeMetrics record the scrapes of a source for Prometheus. A nil
+// *scrapeMetrics records nothing, e.g. if -metrics-addr isn't set.
+type scrapeMetrics struct {
+	itemsScraped   prometheus.Counter
+	newItems       prometheus.Counter
+	parseErrors    prometheus.Counter
+	scrapeDuration prometheus.Histogram
+	lastSuccess    prometheus.Gauge
+}
+
+// newScrapeMetrics creates the metrics of the source and registers them
+// with reg, usually prometheus.DefaultRegisterer.
+func newScrapeMetrics(reg prometheus.Registerer, state string) (*scrapeMetrics, error) {
+	labels := prometheus.Labels{"state": state}
+	m := &scrapeMetrics{
+		itemsScraped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "lmk_items_scraped_total",
+			Help:        "Number of items scraped, including those stored before.",
+			ConstLabels: labels,
+		}),
+		newItems: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "lmk_new_items_total",
+			Help:        "Number of scraped items which haven't been stored before.",
+			ConstLabels: labels,
+		}),
+		parseErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "lmk_parse_errors_total",
+			Help:        "Number of pages which failed to parse, e.g. because the table heading changed.",
+			ConstLabels: labels,
+		}),
+		scrapeDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "lmk_scrape_duration_seconds",
+			Help:        "Duration of fetching and parsing all pages of a scrape.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "lmk_last_success_timestamp_seconds",
+			Help:        "Unix time of the last successful scrape.",
+			ConstLabels: labels,
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.itemsScraped,
+		m.newItems,
+		m.parseErrors,
+		m.scrapeDuration,
+		m.lastSuccess,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *scrapeMetrics) observeScrape(d time.Duration) {
+	if m != nil {
+		m.scrapeDuration.Observe(d.Seconds())
+	}
+}
+
+func (m *scrapeMetrics) addScraped(n int) {
+	if m != nil {
+		m.itemsScraped.Add(float64(n))
+	}
+}
+
+func (m *scrapeMetrics) addNew(n int) {
+	if m != nil {
+		m.newItems.Add(float64(n))
+	}
+}
+
+func (m *scrapeMetrics) addParseError() {
+	if m != nil {
+		m.parseErrors.Inc()
+	}
+}
+
+func (m *scrapeMetrics) setSuccess(t time.Time) {
+	if m != nil {
+		m.lastSuccess.Set(float64(t.Unix()))
+	}
+}
+
+// withMetricsServer serves the metrics of the default registry at
+// /metrics on ln while cmd runs.
+func withMetricsServer(ctx context.Context, l *slog.Logger, ln net.Listener, cmd func() error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- serveHTTP(ctx, l, ln, mux)
+	}()
+
+	err := cmd()
+	cancel()
+
+	return errors.Join(err, <-errc)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The items table before schema versions were tracked, without the
+// schema_version table, see sqliteMigrations
+const baselineSchemaStmt = `
+	create table items (
+		id integer primary key not null,
+		hash text unique not null,
+		authority text not null,
+		published_at text not null,
+		found_at text not null,
+		name text not null,
+		address text not null,
+		reason text not null,
+		legal_basis text not null,
+		info text not null
+	) strict;
+`
+
+const baselineInsertStmt = `
+	insert into items (
+		hash,
+		authority,
+		published_at,
+		found_at,
+		name,
+		address,
+		reason,
+		legal_basis,
+		info
+	) values (
+		?, ?, ?, ?, ?, ?, ?, ?, ?
+	);
+`
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// createBaselineSQLite creates an sqlite database of the baseline schema
+// at path holding the items, stored with the given hashes.
+func createBaselineSQLite(ctx context.Context, t *testing.T, path string, hashes []string, items []*item) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", sqliteDSN(sqliteConfig{file: path}))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	if _, err := db.ExecContext(ctx, baselineSchemaStmt); err != nil {
+		t.Fatalf("failed to create baseline schema: %v", err)
+	}
+	for i, itm := range items {
+		if _, err := db.ExecContext(
+			ctx,
+			baselineInsertStmt,
+			hashes[i],
+			itm.Authority,
+			itm.PublishedAt,
+			itm.FoundAt,
+			itm.Name,
+			itm.Address,
+			itm.Reason,
+			itm.LegalBasis,
+			itm.Info,
+		); err != nil {
+			t.Fatalf("failed to insert baseline item: %v", err)
+		}
+	}
+}
+
+func TestMigrateBaselineSQLite(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	found := time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC)
+	// Only Baden-Württemberg was scraped, the state isn't stored but
+	// hashed after migrating
+	bakery := &item{
+		State:       "bw",
+		Authority:   "Landratsamt Esslingen",
+		PublishedAt: published,
+		FoundAt:     found,
+		Name:        "Bäckerei Müller",
+		Address:     "Hauptstraße 1, 73728 Esslingen am Neckar",
+		Reason:      "Mäusekot in der Backstube",
+		LegalBasis:  "§ 11 LFGB",
+		Info:        "Mängel beseitigt",
+	}
+	butcher := &item{
+		State:       "bw",
+		Authority:   "Stadt Stuttgart",
+		PublishedAt: published.AddDate(0, 0, 1),
+		FoundAt:     found.AddDate(0, 0, 1),
+		Name:        "Metzgerei Schmid",
+		Address:     "Königstraße 10, 70173 Stuttgart",
+		Reason:      "Kühlkette unterbrochen",
+		LegalBasis:  "§ 11 LFGB",
+		Info:        "",
+	}
+	// The hashes were of the gob-encoded items including their date
+	// strings, the bakery's was stored twice with different ones
+	createBaselineSQLite(
+		ctx,
+		t,
+		path,
+		[]string{"gob-1", "gob-2", "gob-3"},
+		[]*item{bakery, bakery, butcher},
+	)
+
+	st, err := openSQLite(ctx, l, sqliteConfig{file: path})
+	if err != nil {
+		t.Fatalf("failed to migrate baseline database: %v", err)
+	}
+	defer st.close(ctx, l)
+
+	var version int
+	if err := st.db.QueryRowContext(ctx, selectSchemaVersionStmt).Scan(&version); err != nil {
+		t.Fatalf("failed to get schema version: %v", err)
+	}
+	if want := len(sqliteMigrations()); version != want {
+		t.Errorf("got schema version %d, want %d", version, want)
+	}
+
+	columns, err := queryStrings(ctx, l, st.db, selectItemsColumnsStmt)
+	if err != nil {
+		t.Fatalf("failed to get columns of items: %v", err)
+	}
+	for _, c := range []string{
+		"id",
+		"hash",
+		"authority",
+		"published_at",
+		"found_at",
+		"name",
+		"address",
+		"reason",
+		"legal_basis",
+		"info",
+		"first_seen",
+		"last_seen",
+		"state",
+		"published_at_end",
+		"found_at_end",
+		"latitude",
+		"longitude",
+		"street",
+		"postal_code",
+		"city",
+		"authority_normalized",
+		"deleted_at",
+	} {
+		if !slices.Contains(columns, c) {
+			t.Errorf("migrated items table has no column %q", c)
+		}
+	}
+
+	hashes, err := queryStrings(ctx, l, st.db, `select hash from items order by id;`)
+	if err != nil {
+		t.Fatalf("failed to get hashes: %v", err)
+	}
+	if want := []string{itemHash(bakery), itemHash(butcher)}; !slices.Equal(hashes, want) {
+		t.Errorf("got hashes %q, want %q of the deduplicated items", hashes, want)
+	}
+
+	var got []*item
+	if err := st.eachItem(ctx, l, func(itm *item) error {
+		got = append(got, itm)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to get migrated items: %v", err)
+	}
+	if len(got) != 2 { //nolint:mnd // The bakery's duplicate is deleted
+		t.Fatalf("got %d migrated items, want 2", len(got))
+	}
+	for i, want := range []struct {
+		name, street, postalCode, city, authority string
+		publishedAt                               time.Time
+	}{
+		{"Bäckerei Müller", "Hauptstraße 1", "73728", "Esslingen am Neckar", "Landratsamt Esslingen", published},
+		{"Metzgerei Schmid", "Königstraße 10", "70173", "Stuttgart", "Stadt Stuttgart", published.AddDate(0, 0, 1)},
+	} {
+		itm := got[i]
+		if itm.Name != want.name {
+			t.Errorf("item %d: got name %q, want %q", i, itm.Name, want.name)
+		}
+		if itm.State != "bw" {
+			t.Errorf("item %d: got state %q, want %q", i, itm.State, "bw")
+		}
+		if itm.Street != want.street || itm.PostalCode != want.postalCode || itm.City != want.city {
+			t.Errorf(
+				"item %d: got address parts %q, %q, %q, want %q, %q, %q",
+				i, itm.Street, itm.PostalCode, itm.City, want.street, want.postalCode, want.city,
+			)
+		}
+		if itm.AuthorityNormalized != want.authority {
+			t.Errorf("item %d: got normalized authority %q, want %q", i, itm.AuthorityNormalized, want.authority)
+		}
+		if !itm.PublishedAt.Equal(want.publishedAt) {
+			t.Errorf("item %d: got published at %v, want %v", i, itm.PublishedAt, want.publishedAt)
+		}
+		if !itm.FirstSeen.IsZero() || !itm.PublishedAtEnd.IsZero() || !itm.DeletedAt.IsZero() {
+			t.Errorf("item %d: got times set which were unknown before", i)
+		}
+	}
+
+	matches, err := st.searchItems(ctx, l, []string{"mäusekot"})
+	if err != nil {
+		t.Fatalf("failed to search migrated items: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Name != bakery.Name {
+		t.Errorf("got %d items searching the full-text index, want the bakery", len(matches))
+	}
+
+	// Migrating again doesn't change anything
+	st2, err := openSQLite(ctx, l, sqliteConfig{file: path})
+	if err != nil {
+		t.Fatalf("failed to reopen migrated database: %v", err)
+	}
+	defer st2.close(ctx, l)
+	var n int
+	if err := st2.db.QueryRowContext(ctx, countItemsStmt).Scan(&n); err != nil {
+		t.Fatalf("failed to count items: %v", err)
+	}
+	if n != 2 { //nolint:mnd // See above
+		t.Errorf("got %d items after migrating again, want 2", n)
+	}
+}
+
+// tableInfo returns the columns of the items table, one string each
+// holding all of its properties, and the names of its indexes.
+func tableInfo(ctx context.Context, t *testing.T, db *sql.DB) ([]string, []string) {
+	t.Helper()
+
+	l := testLogger()
+	columns, err := queryStrings(ctx, l, db, `
+		select cid || ' ' || name || ' ' || type || ' ' || "notnull" || ' ' || coalesce(dflt_value, 'null') || ' ' || pk
+		from pragma_table_info('items') order by cid;
+	`)
+	if err != nil {
+		t.Fatalf("failed to get table info: %v", err)
+	}
+	// Created explicitly, not by unique constraints
+	indexes, err := queryStrings(ctx, l, db, `
+		select name from pragma_index_list('items') where origin = 'c' order by name;
+	`)
+	if err != nil {
+		t.Fatalf("failed to get index list: %v", err)
+	}
+
+	return columns, indexes
+}
+
+func TestSQLiteCreateSchemaMatchesMigrations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+	dir := t.TempDir()
+
+	migrated, err := openSQLite(ctx, l, sqliteConfig{file: filepath.Join(dir, "migrated.sqlite")})
+	if err != nil {
+		t.Fatalf("failed to create migrated database: %v", err)
+	}
+	defer migrated.close(ctx, l)
+
+	created, err := sql.Open("sqlite", sqliteDSN(sqliteConfig{file: filepath.Join(dir, "created.sqlite")}))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() {
+		if err := created.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+	if _, err := created.ExecContext(ctx, sqliteCreateSchemaStmt); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	wantColumns, wantIndexes := tableInfo(ctx, t, migrated.db)
+	gotColumns, gotIndexes := tableInfo(ctx, t, created)
+	if !slices.Equal(gotColumns, wantColumns) {
+		t.Errorf("got columns\n%q\nof sqliteCreateSchemaStmt, want the migrated\n%q", gotColumns, wantColumns)
+	}
+	if !slices.Equal(gotIndexes, wantIndexes) {
+		t.Errorf("got indexes %q of sqliteCreateSchemaStmt, want the migrated %q", gotIndexes, wantIndexes)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Bounds the response bodies of notification services read
+const notifyMaxResponseSize = 1 << 20
+
+// Returned by notifiers which decided not to notify, e.g. about too few
+// items
+var errNotifySkipped = errors.New("notification skipped")
+
+// notifier notifies about new items, e.g. by sending a chat message.
+type notifier interface {
+	// Name describes the notifier in messages, e.g. "Telegram".
+	Name() string
+	// Notify notifies about the items, of which there is at least one.
+	Notify(ctx context.Context, items []*item) error
+}
+
+// notifierCloser is implemented by notifiers holding on to resources,
+// e.g. connections, which must be released on shutdown.
+type notifierCloser interface {
+	// Close releases the resources, flushing pending notifications.
+	Close(ctx context.Context) error
+}
+
+// newNotifiers returns the notifiers configured by environment variables,
+// see configEnvVars. pageURL is linked in the notifications if supported.
+func newNotifiers(
+	l *slog.Logger,
+	cfg *config,
+	client *http.Client,
+	pageURL string,
+	timeout time.Duration,
+) ([]notifier, error) {
+	var notifiers []notifier
+
+	if token := cfg.getenv("TELEGRAM_BOT_TOKEN", ""); token != "" {
+		chatID := cfg.getenv("TELEGRAM_CHAT_ID", "")
+		if chatID == "" {
+			return nil, errors.New("TELEGRAM_CHAT_ID must be set along with TELEGRAM_BOT_TOKEN")
+		}
+		notifiers = append(notifiers, newTelegramNotifier(
+			l,
+			client,
+			cfg.getenv("TELEGRAM_API_URL", defaultTelegramAPIURL),
+			token,
+			chatID,
+			pageURL,
+		))
+	}
+	if webhookURL := cfg.getenv("SLACK_WEBHOOK_URL", ""); webhookURL != "" {
+		notifiers = append(notifiers, newSlackNotifier(l, client, webhookURL))
+	}
+	if webhookURL := cfg.getenv("DISCORD_WEBHOOK_URL", ""); webhookURL != "" {
+		notifiers = append(notifiers, newDiscordNotifier(l, client, webhookURL))
+	}
+	if token := cfg.getenv("MATRIX_ACCESS_TOKEN", ""); token != "" {
+		homeserverURL, roomID := cfg.getenv("MATRIX_HOMESERVER_URL", ""), cfg.getenv("MATRIX_ROOM_ID", "")
+		if homeserverURL == "" || roomID == "" {
+			return nil, errors.New("MATRIX_HOMESERVER_URL and MATRIX_ROOM_ID must be set along with MATRIX_ACCESS_TOKEN")
+		}
+		notifiers = append(notifiers, newMatrixNotifier(l, client, homeserverURL, token, roomID))
+	}
+	if topic := cfg.getenv("NTFY_TOPIC", ""); topic != "" {
+		notifiers = append(notifiers, newNtfyNotifier(
+			l,
+			client,
+			cfg.getenv("NTFY_SERVER", defaultNtfyServerURL),
+			topic,
+			cfg.getenv("NTFY_TOKEN", ""),
+			cfg.getenv("NTFY_PRIORITY", ""),
+			cfg.getenv("NTFY_TAGS", ""),
+			pageURL,
+		))
+	}
+	if host := cfg.getenv("SMTP_HOST", ""); host != "" {
+		from, to := cfg.getenv("SMTP_FROM", ""), splitList(cfg.getenv("SMTP_TO", ""))
+		if from == "" || len(to) == 0 {
+			return nil, errors.New("SMTP_FROM and SMTP_TO must be set along with SMTP_HOST")
+		}
+		notifiers = append(notifiers, newSMTPNotifier(
+			l,
+			host,
+			cfg.getenv("SMTP_PORT", defaultSMTPPort),
+			cfg.getenv("SMTP_USERNAME", ""),
+			cfg.getenv("SMTP_PASSWORD", ""),
+			from,
+			to,
+			cfg.getenv("SMTP_ATTACH_CSV", "false") == "true",
+			timeout,
+		))
+	}
+
+	if sid := cfg.getenv("TWILIO_ACCOUNT_SID", ""); sid != "" {
+		token, from, to := cfg.getenv("TWILIO_AUTH_TOKEN", ""), cfg.getenv("TWILIO_FROM", ""), cfg.getenv("TWILIO_TO", "")
+		if token == "" || from == "" || to == "" {
+			return nil, errors.New("TWILIO_AUTH_TOKEN, TWILIO_FROM and TWILIO_TO must be set along with TWILIO_ACCOUNT_SID")
+		}
+		threshold, err := strconv.Atoi(cfg.getenv("TWILIO_THRESHOLD", "0"))
+		if err != nil || threshold < 0 {
+			return nil, errors.New("TWILIO_THRESHOLD must be a non-negative number")
+		}
+		notifiers = append(notifiers, newTwilioNotifier(
+			l,
+			client,
+			cfg.getenv("TWILIO_API_URL", defaultTwilioAPIURL),
+			sid,
+			token,
+			from,
+			to,
+			threshold,
+		))
+	}
+	if brokers := splitList(cfg.getenv("KAFKA_BROKERS", "")); len(brokers) > 0 {
+		topic := cfg.getenv("KAFKA_TOPIC", "")
+		if topic == "" {
+			return nil, errors.New("KAFKA_TOPIC must be set along with KAFKA_BROKERS")
+		}
+		n, err := newKafkaNotifier(l, brokers, topic, timeout)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	if cfg.getenv("DESKTOP_NOTIFY", "false") == "true" {
+		maxNotifications, err := strconv.Atoi(cfg.getenv("DESKTOP_NOTIFY_MAX", strconv.Itoa(defaultDesktopNotifyMax)))
+		if err != nil || maxNotifications <= 0 {
+			return nil, errors.New("DESKTOP_NOTIFY_MAX must be a positive number")
+		}
+		if hasDisplay() {
+			notifiers = append(notifiers, newDesktopNotifier(l, maxNotifications))
+		} else {
+			l.Warn("no display available, not raising desktop notifications")
+		}
+	}
+
+	return notifiers, nil
+}
+
+// splitList splits the comma-separated list s, skipping empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// notifyItems notifies about the items using all notifiers. Failures are
+// logged only, they must not fail the scrape.
+func notifyItems(ctx context.Context, l *slog.Logger, notifiers []notifier, items []*item) {
+	if len(items) == 0 {
+		return
+	}
+
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, items); errors.Is(err, errNotifySkipped) {
+			l.DebugContext(
+				ctx,
+				err.Error(),
+				"notifier", n.Name(),
+			)
+			continue
+		} else if err != nil {
+			l.ErrorContext(
+				ctx,
+				err.Error(),
+				"notifier", n.Name(),
+			)
+			continue
+		}
+		l.InfoContext(
+			ctx,
+			"sent notification",
+			"notifier", n.Name(),
+			"items", len(items),
+		)
+	}
+}
+
+// closeNotifiers closes the notifiers implementing notifierCloser.
+// Failures are logged only.
+func closeNotifiers(ctx context.Context, l *slog.Logger, notifiers []notifier) {
+	for _, n := range notifiers {
+		c, ok := n.(notifierCloser)
+		if !ok {
+			continue
+		}
+		if err := c.Close(ctx); err != nil {
+			l.ErrorContext(
+				ctx,
+				err.Error(),
+				"notifier", n.Name(),
+			)
+		}
+	}
+}
+
+// postJSON posts payload JSON-encoded to endpoint and returns the response
+// body. Responses other than 2xx are errors. As the endpoint may contain
+// a secret, e.g. a token, it isn't part of the errors returned.
+func postJSON(
+	ctx context.Context,
+	l *slog.Logger,
+	client *http.Client,
+	endpoint string,
+	payload any,
+) ([]byte, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.New("failed to create request, invalid endpoint")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doNotifyRequest(ctx, l, client, req)
+}
+
+// doNotifyRequest sends req and returns the response body. Responses
+// other than 2xx are errors. The URL of req isn't part of the errors
+// returned, see postJSON.
+func doNotifyRequest(ctx context.Context, l *slog.Logger, client *http.Client, req *http.Request) ([]byte, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("failed to post: %w", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close body: %w", err).Error())
+		}
+	}()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, notifyMaxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		err := error(&statusError{
+			code:   res.StatusCode,
+			status: res.Status,
+		})
+		if details := strings.TrimSpace(string(body)); details != "" {
+			err = fmt.Errorf("%w: %s", err, details)
+		}
+		return body, err
+	}
+
+	return body, nil
+}
+
+// batchTexts joins the texts with sep into as few batches as possible, each
+// at most maxLen characters long. Texts exceeding maxLen on their own are
+// truncated.
+func batchTexts(texts []string, sep string, maxLen int) []string {
+	var (
+		batches []string
+		b       strings.Builder
+		n       int // Characters in b
+	)
+	for _, t := range texts {
+		t = truncateRunes(t, maxLen)
+		tn := utf8.RuneCountInString(t)
+
+		if n > 0 && n+utf8.RuneCountInString(sep)+tn > maxLen {
+			batches = append(batches, b.String())
+			b.Reset()
+			n = 0
+		}
+		if n > 0 {
+			b.WriteString(sep)
+			n += utf8.RuneCountInString(sep)
+		}
+		b.WriteString(t)
+		n += tn
+	}
+	if n > 0 {
+		batches = append(batches, b.String())
+	}
+
+	return batches
+}
+
+// truncateRunes truncates s to at most n characters, the last of which
+// is an ellipsis if truncated.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return capstring(s, max(n-1, 0))
+}
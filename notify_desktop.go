@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+
+	"github.com/gen2brain/beeep"
+)
+
+// Of the notifications raised at once, see DESKTOP_NOTIFY_MAX
+const defaultDesktopNotifyMax = 5
+
+// desktopNotifier raises a native desktop notification per new item, up
+// to a maximum followed by one summarizing the remaining items.
+type desktopNotifier struct {
+	l   *slog.Logger
+	max int
+}
+
+func newDesktopNotifier(l *slog.Logger, maxNotifications int) *desktopNotifier {
+	beeep.AppName = "lmk"
+
+	return &desktopNotifier{
+		l:   l,
+		max: maxNotifications,
+	}
+}
+
+func (n *desktopNotifier) Name() string {
+	return "desktop"
+}
+
+func (n *desktopNotifier) Notify(ctx context.Context, items []*item) error {
+	for i, itm := range items {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to raise desktop notification: %w", err)
+		}
+
+		title, message := itm.Name, itm.Reason
+		if i == n.max-1 && len(items) > n.max {
+			title = "Lebensmittelkontrolle"
+			message = strconv.Itoa(len(items)-i) + " neue Einträge"
+			if i > 0 {
+				message = strconv.Itoa(len(items)-i) + " weitere neue Einträge"
+			}
+		}
+		if err := beeep.Notify(title, message, ""); err != nil {
+			return fmt.Errorf("failed to raise desktop notification: %w", err)
+		}
+		if i == n.max-1 {
+			break
+		}
+	}
+
+	return nil
+}
+
+// hasDisplay reports whether desktop notifications can be shown, i.e.
+// whether a graphical session is available on systems where it may be
+// missing, e.g. on servers.
+func hasDisplay() bool {
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return true
+	}
+	return getenv("DISPLAY", "") != "" || getenv("WAYLAND_DISPLAY", "") != ""
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// See https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	discordMaxEmbeds        = 10
+	discordMaxEmbedsLen     = 6000 // Total characters of all embeds of a message
+	discordMaxTitleLen      = 256
+	discordMaxFieldValueLen = 1024
+
+	discordMinMessageInterval = time.Second
+)
+
+// discordNotifier posts new items to a Discord channel via a webhook, one
+// embed per item.
+type discordNotifier struct {
+	l          *slog.Logger
+	client     *http.Client
+	webhookURL string
+}
+
+func newDiscordNotifier(l *slog.Logger, client *http.Client, webhookURL string) *discordNotifier {
+	return &discordNotifier{
+		l:          l,
+		client:     client,
+		webhookURL: webhookURL,
+	}
+}
+
+func (n *discordNotifier) Name() string {
+	return "Discord"
+}
+
+type discordEmbedField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type discordEmbed struct {
+	Title  string              `json:"title"`
+	Fields []discordEmbedField `json:"fields,omitempty"`
+}
+
+// len returns the number of characters of e counted towards
+// discordMaxEmbedsLen.
+func (e discordEmbed) len() int {
+	n := utf8.RuneCountInString(e.Title)
+	for _, f := range e.Fields {
+		n += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return n
+}
+
+func (n *discordNotifier) Notify(ctx context.Context, items []*item) error {
+	embeds := make([]discordEmbed, 0, len(items))
+	for _, itm := range items {
+		embeds = append(embeds, discordItemEmbed(itm))
+	}
+
+	for i, batch := range batchDiscordEmbeds(embeds) {
+		if i > 0 {
+			if err := waitContext(ctx, discordMinMessageInterval); err != nil {
+				return fmt.Errorf("failed to wait for Discord: %w", err)
+			}
+		}
+
+		payload := struct {
+			Embeds []discordEmbed `json:"embeds"`
+		}{
+			Embeds: batch,
+		}
+		if _, err := postJSON(ctx, n.l, n.client, n.webhookURL, payload); err != nil {
+			return fmt.Errorf("failed to post Discord message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// batchDiscordEmbeds splits the embeds into as few messages as possible
+// without exceeding Discord's limits.
+func batchDiscordEmbeds(embeds []discordEmbed) [][]discordEmbed {
+	var (
+		batches [][]discordEmbed
+		batch   []discordEmbed
+		n       int // Characters in batch
+	)
+	for _, e := range embeds {
+		if len(batch) == discordMaxEmbeds || (len(batch) > 0 && n+e.len() > discordMaxEmbedsLen) {
+			batches = append(batches, batch)
+			batch, n = nil, 0
+		}
+		batch = append(batch, e)
+		n += e.len()
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+func discordItemEmbed(itm *item) discordEmbed {
+	e := discordEmbed{
+		Title: truncateRunes(itm.Name, discordMaxTitleLen),
+	}
+	for _, f := range []discordEmbedField{
+		{labelAuthority, itm.Authority},
+		{labelReason, itm.Reason},
+		{labelLegalBasis, itm.LegalBasis},
+	} {
+		// Discord rejects empty values
+		if f.Value == "" {
+			continue
+		}
+		f.Value = truncateRunes(f.Value, discordMaxFieldValueLen)
+		e.Fields = append(e.Fields, f)
+	}
+	return e
+}
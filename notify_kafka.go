@@ -0,0 +1,93 @@
+//go:build kafka
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Bounds waiting for more messages to batch, the new items are written
+// at once anyway
+const kafkaBatchTimeout = 50 * time.Millisecond
+
+// kafkaNotifier produces each new item as a JSON message to a Kafka topic,
+// keyed by the item's hash. Messages of the same item thus end up in the
+// same partition and may be deduplicated by consumers or log compaction.
+type kafkaNotifier struct {
+	l *slog.Logger
+	w *kafka.Writer
+}
+
+func newKafkaNotifier(
+	l *slog.Logger,
+	brokers []string,
+	topic string,
+	timeout time.Duration,
+) (notifier, error) {
+	return &kafkaNotifier{
+		l: l,
+		w: &kafka.Writer{
+			Addr:  kafka.TCP(brokers...),
+			Topic: topic,
+			// Partitions like the Java client's default partitioner
+			Balancer:     kafka.Murmur2Balancer{},
+			BatchTimeout: kafkaBatchTimeout,
+			WriteTimeout: timeout,
+			RequiredAcks: kafka.RequireAll,
+		},
+	}, nil
+}
+
+func (n *kafkaNotifier) Name() string {
+	return "Kafka"
+}
+
+func (n *kafkaNotifier) Notify(ctx context.Context, items []*item) error {
+	msgs := make([]kafka.Message, 0, len(items))
+	for _, itm := range items {
+		b, err := json.Marshal(itm)
+		if err != nil {
+			return fmt.Errorf("failed to encode Kafka message: %w", err)
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:   []byte(itemHash(itm)),
+			Value: b,
+		})
+	}
+
+	if err := n.w.WriteMessages(ctx, msgs...); err != nil {
+		var werrs kafka.WriteErrors
+		if errors.As(err, &werrs) {
+			return fmt.Errorf("failed to produce %d of %d Kafka messages: %w", werrs.Count(), len(msgs), err)
+		}
+		return fmt.Errorf("failed to produce Kafka messages: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes the pending messages, if any, and closes the connections
+// to the brokers.
+func (n *kafkaNotifier) Close(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- n.w.Close()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to close Kafka producer: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to close Kafka producer: %w", ctx.Err())
+	}
+}
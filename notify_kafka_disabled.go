@@ -0,0 +1,20 @@
+//go:build !kafka
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// newKafkaNotifier fails as Kafka support isn't linked unless built with
+// the kafka tag, see notify_kafka.go.
+func newKafkaNotifier(
+	_ *slog.Logger,
+	_ []string,
+	_ string,
+	_ time.Duration,
+) (notifier, error) {
+	return nil, errors.New("KAFKA_BROKERS is set but lmk was built without Kafka support, rebuild with -tags kafka")
+}
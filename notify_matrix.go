@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Keeps the messages well below the maximum event size of 64 KiB
+	matrixMaxItemsPerMessage = 20
+
+	matrixMinMessageInterval = time.Second
+)
+
+// matrixNotifier posts new items to a Matrix room via the client-server
+// API, see https://spec.matrix.org/latest/client-server-api/#put_matrixclientv3roomsroomidsendeventtypetxnid.
+type matrixNotifier struct {
+	l             *slog.Logger
+	client        *http.Client
+	homeserverURL string
+	accessToken   string
+	roomID        string
+}
+
+func newMatrixNotifier(
+	l *slog.Logger,
+	client *http.Client,
+	homeserverURL string,
+	accessToken string,
+	roomID string,
+) *matrixNotifier {
+	return &matrixNotifier{
+		l:             l,
+		client:        client,
+		homeserverURL: strings.TrimSuffix(homeserverURL, "/"),
+		accessToken:   accessToken,
+		roomID:        roomID,
+	}
+}
+
+func (n *matrixNotifier) Name() string {
+	return "Matrix"
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func (n *matrixNotifier) Notify(ctx context.Context, items []*item) error {
+	// Identifies the messages of this call, so retried requests aren't
+	// posted twice
+	txnPrefix := "lmk." + strconv.FormatInt(time.Now().UnixNano(), 10) + "."
+
+	i := 0
+	for chunk := range slices.Chunk(items, matrixMaxItemsPerMessage) {
+		if i > 0 {
+			if err := waitContext(ctx, matrixMinMessageInterval); err != nil {
+				return fmt.Errorf("failed to wait for Matrix: %w", err)
+			}
+		}
+
+		if err := n.send(ctx, txnPrefix+strconv.Itoa(i), matrixItemsMessage(chunk)); err != nil {
+			return fmt.Errorf("failed to send Matrix message: %w", err)
+		}
+		i++
+	}
+
+	return nil
+}
+
+func (n *matrixNotifier) send(ctx context.Context, txnID string, msg matrixMessage) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	endpoint := n.homeserverURL + "/_matrix/client/v3/rooms/" + url.PathEscape(n.roomID) +
+		"/send/m.room.message/" + url.PathEscape(txnID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return errors.New("failed to create request, invalid homeserver URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.accessToken)
+
+	_, err = doNotifyRequest(ctx, n.l, n.client, req)
+	return err
+}
+
+// matrixItemsMessage lists the items by name and reason, in plain text
+// and HTML.
+func matrixItemsMessage(items []*item) matrixMessage {
+	var body, formatted strings.Builder
+	for i, itm := range items {
+		if i > 0 {
+			body.WriteString("\n\n")
+		}
+		body.WriteString(itm.Name)
+		formatted.WriteString("<p><strong>" + html.EscapeString(itm.Name) + "</strong>")
+		if itm.Reason != "" {
+			body.WriteString("\n" + itm.Reason)
+			formatted.WriteString("<br>" + strings.ReplaceAll(html.EscapeString(itm.Reason), "\n", "<br>"))
+		}
+		formatted.WriteString("</p>")
+	}
+
+	return matrixMessage{
+		MsgType:       "m.text",
+		Body:          body.String(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted.String(),
+	}
+}
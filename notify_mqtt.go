@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	defaultMQTTTopic = "lmk/items"
+
+	// Bounds waiting for unacknowledged messages when disconnecting
+	mqttDisconnectQuiesce = time.Second
+)
+
+// mqttNotifier publishes each new item as a JSON object to a topic of an
+// MQTT broker. It connects for each notification only, as scrapes are
+// rare.
+type mqttNotifier struct {
+	l      *slog.Logger
+	opts   *mqtt.ClientOptions
+	topic  string
+	qos    byte
+	retain bool
+}
+
+func newMQTTNotifier(
+	l *slog.Logger,
+	broker string,
+	clientID string,
+	username string,
+	password string,
+	topic string,
+	qos byte,
+	retain bool,
+	timeout time.Duration,
+) *mqttNotifier {
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		// The broker assigns one if empty
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetConnectTimeout(timeout).
+		SetWriteTimeout(timeout).
+		SetAutoReconnect(false)
+
+	return &mqttNotifier{
+		l:      l,
+		opts:   opts,
+		topic:  topic,
+		qos:    qos,
+		retain: retain,
+	}
+}
+
+func (n *mqttNotifier) Name() string {
+	return "MQTT"
+}
+
+func (n *mqttNotifier) Notify(ctx context.Context, items []*item) error {
+	client := mqtt.NewClient(n.opts)
+	if err := waitMQTT(ctx, client.Connect()); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+	defer func() {
+		client.Disconnect(uint(mqttQuiesce(ctx).Milliseconds()))
+	}()
+
+	var failed int
+	for _, itm := range items {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to publish MQTT messages: %w", err)
+		}
+
+		if err := n.publish(ctx, client, itm); err != nil {
+			failed++
+			n.l.ErrorContext(
+				ctx,
+				err.Error(),
+				"name", itm.Name,
+			)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to publish %d of %d MQTT messages", failed, len(items))
+	}
+
+	return nil
+}
+
+func (n *mqttNotifier) publish(ctx context.Context, client mqtt.Client, itm *item) error {
+	b, err := json.Marshal(itm)
+	if err != nil {
+		return fmt.Errorf("failed to encode MQTT message: %w", err)
+	}
+
+	if err := waitMQTT(ctx, client.Publish(n.topic, n.qos, n.retain, b)); err != nil {
+		return fmt.Errorf("failed to publish MQTT message: %w", err)
+	}
+
+	return nil
+}
+
+// waitMQTT waits for the operation of t to complete, or the context to
+// be done.
+func waitMQTT(ctx context.Context, t mqtt.Token) error {
+	select {
+	case <-t.Done():
+		return t.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mqttQuiesce returns how long to wait for unacknowledged messages when
+// disconnecting, not beyond the deadline of the context.
+func mqttQuiesce(ctx context.Context) time.Duration {
+	if ctx.Err() != nil {
+		return 0
+	}
+	d := mqttDisconnectQuiesce
+	if deadline, ok := ctx.Deadline(); ok {
+		d = min(d, time.Until(deadline))
+	}
+	return max(d, 0)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const defaultNtfyServerURL = "https://ntfy.sh"
+
+// ntfyNotifier publishes each new item as a message to an ntfy topic,
+// see https://docs.ntfy.sh/publish/.
+type ntfyNotifier struct {
+	l        *slog.Logger
+	client   *http.Client
+	topicURL string
+	// Access token of the topic, anonymous if empty
+	token string
+	// Optional, e.g. "high" or "4"
+	priority string
+	// Optional, comma-separated, e.g. "warning,restaurant"
+	tags string
+	// Opened when a message is clicked
+	pageURL string
+}
+
+func newNtfyNotifier(
+	l *slog.Logger,
+	client *http.Client,
+	serverURL string,
+	topic string,
+	token string,
+	priority string,
+	tags string,
+	pageURL string,
+) *ntfyNotifier {
+	return &ntfyNotifier{
+		l:        l,
+		client:   client,
+		topicURL: strings.TrimSuffix(serverURL, "/") + "/" + topic,
+		token:    token,
+		priority: priority,
+		tags:     tags,
+		pageURL:  pageURL,
+	}
+}
+
+func (n *ntfyNotifier) Name() string {
+	return "ntfy"
+}
+
+func (n *ntfyNotifier) Notify(ctx context.Context, items []*item) error {
+	for _, itm := range items {
+		if err := n.publish(ctx, itm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *ntfyNotifier) publish(ctx context.Context, itm *item) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.topicURL, strings.NewReader(itm.Reason))
+	if err != nil {
+		return errors.New("failed to create ntfy request, invalid server URL or topic")
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	// Header values are ASCII only, ntfy decodes RFC 2047 encoded words
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", itm.Name))
+	req.Header.Set("Click", n.pageURL)
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	if n.priority != "" {
+		req.Header.Set("Priority", n.priority)
+	}
+	if n.tags != "" {
+		req.Header.Set("Tags", n.tags)
+	}
+
+	if _, err := doNotifyRequest(ctx, n.l, n.client, req); err != nil {
+		return fmt.Errorf("failed to publish ntfy message: %w", err)
+	}
+
+	return nil
+}
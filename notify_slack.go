@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// Of a message and of the text of a section block, see
+	// https://api.slack.com/reference/block-kit/blocks
+	slackMaxBlocks  = 50
+	slackMaxTextLen = 3000
+
+	// See https://api.slack.com/docs/rate-limits#incoming-webhooks
+	slackMinMessageInterval = time.Second
+)
+
+// Escapes the characters with a special meaning in Slack's mrkdwn
+//
+//nolint:gochecknoglobals // It's a constant replacer
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackNotifier posts new items to a Slack channel via an Incoming
+// Webhook, one section block per item.
+type slackNotifier struct {
+	l          *slog.Logger
+	client     *http.Client
+	webhookURL string
+}
+
+func newSlackNotifier(l *slog.Logger, client *http.Client, webhookURL string) *slackNotifier {
+	return &slackNotifier{
+		l:          l,
+		client:     client,
+		webhookURL: webhookURL,
+	}
+}
+
+func (n *slackNotifier) Name() string {
+	return "Slack"
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string    `json:"type"`
+	Text slackText `json:"text"`
+}
+
+func (n *slackNotifier) Notify(ctx context.Context, items []*item) error {
+	i := 0
+	for chunk := range slices.Chunk(items, slackMaxBlocks) {
+		if i > 0 {
+			if err := waitContext(ctx, slackMinMessageInterval); err != nil {
+				return fmt.Errorf("failed to wait for Slack: %w", err)
+			}
+		}
+		i++
+
+		blocks := make([]slackBlock, 0, len(chunk))
+		for _, itm := range chunk {
+			blocks = append(blocks, slackBlock{
+				Type: "section",
+				Text: slackText{
+					Type: "mrkdwn",
+					Text: truncateRunes(slackItemText(itm), slackMaxTextLen),
+				},
+			})
+		}
+		payload := struct {
+			// Shown in notifications, which don't render blocks
+			Text   string       `json:"text"`
+			Blocks []slackBlock `json:"blocks"`
+		}{
+			Text:   fmt.Sprintf("%d new items", len(chunk)),
+			Blocks: blocks,
+		}
+
+		if _, err := postJSON(ctx, n.l, n.client, n.webhookURL, payload); err != nil {
+			return fmt.Errorf("failed to post Slack message: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// slackItemText summarizes itm in mrkdwn.
+func slackItemText(itm *item) string {
+	var b strings.Builder
+	b.WriteString("*")
+	b.WriteString(slackEscaper.Replace(itm.Name))
+	b.WriteString("*")
+	for _, s := range []string{itm.Authority, itm.Address, itm.Reason} {
+		if s != "" {
+			b.WriteString("\n")
+			b.WriteString(slackEscaper.Replace(s))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSMTPPort = "587"
+
+	smtpAttachmentName = "lmk.csv"
+	// Of the base64-encoded attachment, see RFC 2045 section 6.8
+	smtpMaxLineLength = 76
+)
+
+// smtpNotifier emails a digest of the new items, in plain text and HTML,
+// optionally with the items attached as CSV. The connection must support
+// STARTTLS.
+type smtpNotifier struct {
+	l        *slog.Logger
+	host     string
+	port     string
+	username string
+	// Authenticates if username is set
+	password  string
+	from      string
+	to        []string
+	attachCSV bool
+	// Of sending the email
+	timeout time.Duration
+}
+
+func newSMTPNotifier(
+	l *slog.Logger,
+	host string,
+	port string,
+	username string,
+	password string,
+	from string,
+	to []string,
+	attachCSV bool,
+	timeout time.Duration,
+) *smtpNotifier {
+	return &smtpNotifier{
+		l:         l,
+		host:      host,
+		port:      port,
+		username:  username,
+		password:  password,
+		from:      from,
+		to:        to,
+		attachCSV: attachCSV,
+		timeout:   timeout,
+	}
+}
+
+func (n *smtpNotifier) Name() string {
+	return "SMTP"
+}
+
+func (n *smtpNotifier) Notify(ctx context.Context, items []*item) error {
+	msg, err := n.message(items, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	if err := n.send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+func (n *smtpNotifier) send(ctx context.Context, msg []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(n.host, n.port))
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	// net/smtp isn't context-aware, bound and interrupt the connection
+	// instead
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			n.closeConn(ctx, conn)
+			return fmt.Errorf("failed to set deadline: %w", err)
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { n.closeConn(ctx, conn) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		n.closeConn(ctx, conn)
+		return contextError(ctx, fmt.Errorf("failed to greet: %w", err))
+	}
+	if err := n.deliver(c, msg); err != nil {
+		if err := c.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			n.l.ErrorContext(ctx, fmt.Errorf("failed to close SMTP connection: %w", err).Error())
+		}
+		return contextError(ctx, err)
+	}
+	if err := c.Quit(); err != nil {
+		return fmt.Errorf("failed to quit: %w", err)
+	}
+
+	return nil
+}
+
+// contextError attributes err to ctx being done, if it is, as the
+// connection was closed because of it.
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return err
+}
+
+func (n *smtpNotifier) closeConn(ctx context.Context, conn net.Conn) {
+	if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		n.l.ErrorContext(ctx, fmt.Errorf("failed to close SMTP connection: %w", err).Error())
+	}
+}
+
+// deliver sends msg using c, which is upgraded to TLS first.
+func (n *smtpNotifier) deliver(c *smtp.Client, msg []byte) error {
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return errors.New("server doesn't support STARTTLS")
+	}
+	if err := c.StartTLS(&tls.Config{
+		ServerName: n.host,
+		MinVersion: tls.VersionTLS12,
+	}); err != nil {
+		return fmt.Errorf("failed to start TLS: %w", err)
+	}
+
+	if n.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := c.Mail(n.from); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range n.to {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %q: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish data: %w", err)
+	}
+
+	return nil
+}
+
+// message builds the email listing the items.
+func (n *smtpNotifier) message(items []*item, now time.Time) ([]byte, error) {
+	alt, altContentType, err := smtpAlternativeBody(items)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := "Lebensmittelkontrolle: 1 neuer Eintrag"
+	if len(items) != 1 {
+		subject = "Lebensmittelkontrolle: " + strconv.Itoa(len(items)) + " neue Einträge"
+	}
+
+	var body bytes.Buffer
+	contentType := altContentType
+	if !n.attachCSV {
+		body.Write(alt)
+	} else {
+		mw := multipart.NewWriter(&body)
+		contentType = "multipart/mixed; boundary=" + mw.Boundary()
+
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", altContentType)
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+		if _, err := pw.Write(alt); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+		if err := writeCSVAttachment(mw, items); err != nil {
+			return nil, err
+		}
+		if err := mw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to finish email: %w", err)
+		}
+	}
+
+	var msg bytes.Buffer
+	for _, h := range []struct{ key, value string }{
+		{"From", n.from},
+		{"To", strings.Join(n.to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", contentType},
+	} {
+		msg.WriteString(h.key + ": " + h.value + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// smtpAlternativeBody renders the items as plain text and HTML. It
+// returns the multipart body along with its content type.
+func smtpAlternativeBody(items []*item) ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	columns := defaultTableColumns(true)
+	for _, p := range []struct {
+		contentType string
+		render      func(w io.Writer) error
+	}{
+		{"text/plain; charset=utf-8", func(w io.Writer) error { return renderPlainText(w, items, columns) }},
+		{"text/html; charset=utf-8", func(w io.Writer) error { return renderHTML(w, items, columns, false) }},
+	} {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", p.contentType)
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create email part: %w", err)
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		if err := p.render(qw); err != nil {
+			return nil, "", err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, "", fmt.Errorf("failed to encode email part: %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to finish email part: %w", err)
+	}
+
+	return buf.Bytes(), "multipart/alternative; boundary=" + mw.Boundary(), nil
+}
+
+// renderPlainText prints the non-empty columns of each item as
+// "label: text" lines, the items separated by an empty line.
+func renderPlainText(w io.Writer, items []*item, columns []column) error {
+	for i, itm := range items {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return fmt.Errorf("failed to print: %w", err)
+			}
+		}
+		for _, c := range columns {
+			text := c.text(itm)
+			if text == "" {
+				continue
+			}
+			text = strings.ReplaceAll(text, "\n", "\n  ")
+			if _, err := io.WriteString(w, c.label+": "+text+"\n"); err != nil {
+				return fmt.Errorf("failed to print: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func writeCSVAttachment(mw *multipart.Writer, items []*item) error {
+	var csv bytes.Buffer
+	if err := renderCSV(&csv, items, defaultColumns()); err != nil {
+		return err
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", "text/csv; charset=utf-8")
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": smtpAttachmentName}))
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return fmt.Errorf("failed to create email attachment: %w", err)
+	}
+
+	enc := base64.StdEncoding.EncodeToString(csv.Bytes())
+	for len(enc) > 0 {
+		line := enc[:min(len(enc), smtpMaxLineLength)]
+		enc = enc[len(line):]
+		if _, err := io.WriteString(pw, line+"\r\n"); err != nil {
+			return fmt.Errorf("failed to write email attachment: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultTelegramAPIURL = "https://api.telegram.org"
+
+	// Of a message, see https://core.telegram.org/bots/api#sendmessage
+	telegramMaxMessageLen = 4096
+
+	// See https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+	telegramMinMessageInterval = time.Second
+)
+
+// telegramNotifier sends new items to a Telegram chat via the Bot API,
+// batching as many items as fit into a single message.
+type telegramNotifier struct {
+	l       *slog.Logger
+	client  *http.Client
+	baseURL string
+	token   string
+	chatID  string
+	// Linked in each message
+	pageURL string
+}
+
+func newTelegramNotifier(
+	l *slog.Logger,
+	client *http.Client,
+	baseURL string,
+	token string,
+	chatID string,
+	pageURL string,
+) *telegramNotifier {
+	return &telegramNotifier{
+		l:       l,
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		chatID:  chatID,
+		pageURL: pageURL,
+	}
+}
+
+func (n *telegramNotifier) Name() string {
+	return "Telegram"
+}
+
+func (n *telegramNotifier) Notify(ctx context.Context, items []*item) error {
+	footer := "\n\n" + n.pageURL
+
+	texts := make([]string, 0, len(items))
+	for _, itm := range items {
+		texts = append(texts, telegramItemText(itm))
+	}
+
+	for i, text := range batchTexts(texts, "\n\n", telegramMaxMessageLen-utf8.RuneCountInString(footer)) {
+		if i > 0 {
+			if err := waitContext(ctx, telegramMinMessageInterval); err != nil {
+				return fmt.Errorf("failed to wait for Telegram: %w", err)
+			}
+		}
+		if err := n.send(ctx, text+footer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *telegramNotifier) send(ctx context.Context, text string) error {
+	type linkPreviewOptions struct {
+		IsDisabled bool `json:"is_disabled"`
+	}
+	payload := struct {
+		ChatID             string             `json:"chat_id"`
+		Text               string             `json:"text"`
+		LinkPreviewOptions linkPreviewOptions `json:"link_preview_options"`
+	}{
+		ChatID:             n.chatID,
+		Text:               text,
+		LinkPreviewOptions: linkPreviewOptions{IsDisabled: true},
+	}
+
+	body, err := postJSON(ctx, n.l, n.client, n.baseURL+"/bot"+n.token+"/sendMessage", payload)
+	if err != nil {
+		return fmt.Errorf("failed to send Telegram message: %w", err)
+	}
+
+	var res struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return fmt.Errorf("failed to decode Telegram response: %w", err)
+	}
+	if !res.OK {
+		return fmt.Errorf("failed to send Telegram message: %s", res.Description)
+	}
+
+	return nil
+}
+
+// telegramItemText formats itm compactly as plain text.
+func telegramItemText(itm *item) string {
+	lines := []string{itm.Name}
+	if city := itemCity(itm); city != "" {
+		lines = append(lines, city)
+	}
+	if itm.Reason != "" {
+		lines = append(lines, itm.Reason)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultTwilioAPIURL = "https://api.twilio.com"
+
+	// Of a single-part SMS
+	twilioMaxMessageLen = 160
+)
+
+// twilioNotifier texts a short summary of the new items via Twilio's
+// Messages API, see https://www.twilio.com/docs/messaging/api/message-resource.
+// As SMS are expensive, it only does so for more than a threshold of new
+// items.
+type twilioNotifier struct {
+	l          *slog.Logger
+	client     *http.Client
+	apiURL     string
+	accountSID string
+	authToken  string
+	from       string
+	to         string
+	// Number of new items which must be exceeded
+	threshold int
+}
+
+func newTwilioNotifier(
+	l *slog.Logger,
+	client *http.Client,
+	apiURL string,
+	accountSID string,
+	authToken string,
+	from string,
+	to string,
+	threshold int,
+) *twilioNotifier {
+	return &twilioNotifier{
+		l:          l,
+		client:     client,
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		to:         to,
+		threshold:  threshold,
+	}
+}
+
+func (n *twilioNotifier) Name() string {
+	return "Twilio"
+}
+
+func (n *twilioNotifier) Notify(ctx context.Context, items []*item) error {
+	if len(items) <= n.threshold {
+		return fmt.Errorf("%w, at most %d new items", errNotifySkipped, n.threshold)
+	}
+
+	form := url.Values{
+		"From": {n.from},
+		"To":   {n.to},
+		"Body": {twilioSummary(items)},
+	}
+	endpoint := n.apiURL + "/2010-04-01/Accounts/" + url.PathEscape(n.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.New("failed to create Twilio request, invalid API URL")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(n.accountSID, n.authToken)
+
+	if _, err := doNotifyRequest(ctx, n.l, n.client, req); err != nil {
+		return fmt.Errorf("failed to send Twilio message: %w", err)
+	}
+
+	return nil
+}
+
+// twilioSummary counts the items and lists their cities, the most
+// frequent first, as many as fit into a single SMS.
+func twilioSummary(items []*item) string {
+	msg := strconv.Itoa(len(items)) + " new Lebensmittelkontrolle entries"
+	if len(items) == 1 {
+		msg = "1 new Lebensmittelkontrolle entry"
+	}
+
+	counts := make(map[string]int)
+	for _, itm := range items {
+		if city := itemCity(itm); city != "" {
+			counts[city]++
+		}
+	}
+	cities := make([]string, 0, len(counts))
+	for c := range counts {
+		cities = append(cities, c)
+	}
+	slices.SortFunc(cities, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+
+	for i, c := range cities {
+		sep := ", "
+		if i == 0 {
+			sep = " in "
+		}
+		// Leaves room to mention the remaining cities
+		var more string
+		if remaining := len(cities) - i - 1; remaining > 0 {
+			more = " and " + strconv.Itoa(remaining) + " more"
+		}
+		if i > 0 && utf8.RuneCountInString(msg+sep+c+more) > twilioMaxMessageLen {
+			return msg + " and " + strconv.Itoa(len(cities)-i) + " more"
+		}
+		msg += sep + c
+	}
+
+	return msg
+}
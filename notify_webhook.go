@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// Carries the HMAC-SHA256 of the request body keyed with the webhook
+// secret, hex-encoded and prefixed with "sha256="
+const webhookSignatureHeader = "X-Lmk-Signature-256"
+
+// webhookNotifier posts the new items as a JSON array to an arbitrary
+// endpoint, retrying transient failures.
+type webhookNotifier struct {
+	l         *slog.Logger
+	client    *http.Client
+	url       string
+	userAgent string
+	// Signs the requests if set
+	secret string
+	// Maximum number of requests made
+	attempts int
+}
+
+func newWebhookNotifier(
+	l *slog.Logger,
+	client *http.Client,
+	url string,
+	userAgent string,
+	secret string,
+	attempts int,
+) *webhookNotifier {
+	return &webhookNotifier{
+		l:         l,
+		client:    client,
+		url:       url,
+		userAgent: userAgent,
+		secret:    secret,
+		attempts:  attempts,
+	}
+}
+
+func (n *webhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, items []*item) error {
+	var body bytes.Buffer
+	if err := renderJSONArray(&body, items, nil, false); err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := n.post(ctx, body.Bytes())
+		if err == nil {
+			return nil
+		}
+		if attempt >= n.attempts || !isRetryable(err) || ctx.Err() != nil {
+			return fmt.Errorf("failed to post webhook: %w", err)
+		}
+
+		d := fetchBackoff(attempt)
+		n.l.WarnContext(
+			ctx,
+			"failed to post webhook, retrying",
+			"err", err,
+			"attempt", attempt,
+			"backoff", d.String(),
+		)
+		if err := waitContext(ctx, d); err != nil {
+			return fmt.Errorf("failed to wait for retry: %w", err)
+		}
+	}
+}
+
+func (n *webhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("failed to create request, invalid URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", n.userAgent)
+	if n.secret != "" {
+		req.Header.Set(webhookSignatureHeader, webhookSignature(n.secret, body))
+	}
+
+	_, err = doNotifyRequest(ctx, n.l, n.client, req)
+	return err
+}
+
+// webhookSignature returns the value of webhookSignatureHeader for body.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
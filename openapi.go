@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// Of OpenAPI 3.1, its schemas are JSON Schema 2020-12 like the item
+	// schema, see itemJSONSchema
+	openAPIVersion = "3.1.0"
+	// Of the API described, to be raised on incompatible changes
+	openAPIAPIVersion = "1.0.0"
+)
+
+type openAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       openAPIInfo                `json:"info"`
+	Paths      map[string]openAPIPathItem `json:"paths"`
+	Components openAPIComponents          `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIPathItem struct {
+	Get  *openAPIOperation `json:"get,omitempty"`
+	Post *openAPIOperation `json:"post,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description"`
+	Schema      any    `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema any `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]any `json:"schemas"`
+}
+
+// openAPISpec returns the OpenAPI document describing the routes of the
+// serve subcommand, see newServeMux. The parameters of /items and the
+// item schema are derived from the same definitions as the handlers use,
+// see itemsQueryParams and itemJSONSchema.
+func openAPISpec() (*openAPIDocument, error) {
+	itemSchema, err := itemJSONSchema(nil)
+	if err != nil {
+		return nil, err
+	}
+	// Implied by the document
+	itemSchema.Schema = ""
+
+	ref := func(name string) any {
+		return map[string]string{"$ref": "#/components/schemas/" + name}
+	}
+	jsonContent := func(schema any) map[string]openAPIMediaType {
+		return map[string]openAPIMediaType{"application/json": {Schema: schema}}
+	}
+	errorResponse := func(description string) openAPIResponse {
+		return openAPIResponse{
+			Description: description,
+			Content:     jsonContent(ref("Error")),
+		}
+	}
+
+	var params []openAPIParameter
+	for _, p := range itemsQueryParams(&filterOptions{}) {
+		var schema any
+		switch {
+		case p.str != nil:
+			schema = map[string]string{"type": "string"}
+		case p.strs != nil:
+			schema = map[string]any{"type": "array", "items": map[string]string{"type": "string"}}
+		case p.num != nil:
+			schema = map[string]string{"type": "integer"}
+		case p.flag != nil:
+			schema = map[string]string{"type": "boolean"}
+		default:
+			return nil, fmt.Errorf("no OpenAPI type of query parameter %q", p.name)
+		}
+		params = append(params, openAPIParameter{
+			Name:        p.name,
+			In:          "query",
+			Description: p.description,
+			Schema:      schema,
+		})
+	}
+
+	return &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:   "lmk",
+			Version: openAPIAPIVersion,
+		},
+		Paths: map[string]openAPIPathItem{
+			"/items": {Get: &openAPIOperation{
+				OperationID: "listItems",
+				Summary:     "List the stored items matching the query parameters",
+				Parameters:  params,
+				Responses: map[string]openAPIResponse{
+					strconv.Itoa(http.StatusOK): {
+						Description: "The matching items",
+						Content:     jsonContent(map[string]any{"type": "array", "items": ref("Item")}),
+					},
+					strconv.Itoa(http.StatusBadRequest):          errorResponse("Invalid query parameters"),
+					strconv.Itoa(http.StatusInternalServerError): errorResponse("The items couldn't be queried"),
+				},
+			}},
+			"/graphql": {Post: &openAPIOperation{
+				OperationID: "queryGraphQL",
+				Summary:     "Query the stored items with GraphQL",
+				RequestBody: &openAPIRequestBody{
+					Required: true,
+					Content: jsonContent(map[string]any{
+						"type":     "object",
+						"required": []string{"query"},
+						"properties": map[string]any{
+							"query":         map[string]string{"type": "string"},
+							"operationName": map[string]string{"type": "string"},
+							"variables":     map[string]string{"type": "object"},
+						},
+					}),
+				},
+				Responses: map[string]openAPIResponse{
+					strconv.Itoa(http.StatusOK): {
+						Description: "The GraphQL response, data and errors if any",
+						Content:     jsonContent(map[string]string{"type": "object"}),
+					},
+					strconv.Itoa(http.StatusBadRequest): errorResponse("Invalid GraphQL request"),
+				},
+			}},
+			"/healthz": {Get: &openAPIOperation{
+				OperationID: "checkHealth",
+				Summary:     "Check whether the database is reachable",
+				Responses: map[string]openAPIResponse{
+					strconv.Itoa(http.StatusOK): {
+						Description: "Healthy",
+						Content: map[string]openAPIMediaType{
+							// Not const, which OpenAPI 3.0 tooling rejects
+							"text/plain": {Schema: map[string]any{"type": "string", "enum": []string{"ok\n"}}},
+						},
+					},
+					strconv.Itoa(http.StatusServiceUnavailable): errorResponse("The database is unavailable"),
+				},
+			}},
+			"/openapi.json": {Get: &openAPIOperation{
+				OperationID: "getOpenAPISpec",
+				Summary:     "Get this document",
+				Responses: map[string]openAPIResponse{
+					strconv.Itoa(http.StatusOK): {
+						Description: "The OpenAPI document",
+						Content:     jsonContent(map[string]string{"type": "object"}),
+					},
+				},
+			}},
+		},
+		Components: openAPIComponents{
+			Schemas: map[string]any{
+				"Item": itemSchema,
+				"Error": map[string]any{
+					"type":                 "object",
+					"required":             []string{"error"},
+					"properties":           map[string]any{"error": map[string]string{"type": "string"}},
+					"additionalProperties": false,
+				},
+			},
+		},
+	}, nil
+}
+
+func renderOpenAPISpec(w io.Writer, indent bool) error {
+	spec, err := openAPISpec()
+	if err != nil {
+		return err
+	}
+
+	if err := newJSONEncoder(w, indent).Encode(spec); err != nil {
+		return fmt.Errorf("failed to JSON-print OpenAPI document: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestServedOpenAPISpecValid(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+
+	st, err := openSQLite(ctx, l, sqliteConfig{file: filepath.Join(t.TempDir(), "db.sqlite")})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer st.close(ctx, l)
+
+	mux, err := newServeMux(l, st)
+	if err != nil {
+		t.Fatalf("failed to create serve mux: %v", err)
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/openapi.json", http.NoBody)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to get OpenAPI spec: %v", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			t.Errorf("failed to close body: %v", err)
+		}
+	}()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %s, want 200 OK", res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("failed to read OpenAPI spec: %v", err)
+	}
+
+	// The validator is of OpenAPI 3.0, the document only uses what 3.1
+	// shares with it, and $comment of 3.1's JSON Schema
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(body)
+	if err != nil {
+		t.Fatalf("failed to load OpenAPI spec: %v", err)
+	}
+	if err := doc.Validate(loader.Context, openapi3.AllowExtraSiblingFields("$comment")); err != nil {
+		t.Errorf("invalid OpenAPI spec: %v", err)
+	}
+	for _, path := range []string{"/items", "/healthz", "/openapi.json", "/graphql"} {
+		if doc.Paths.Find(path) == nil {
+			t.Errorf("OpenAPI spec doesn't describe %s", path)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+const outputFilePermissions = 0o644
+
+type outputFormat int
+
+const (
+	outputFormatTable outputFormat = iota
+	outputFormatJSON               // Newline-delimited, one object per line
+	outputFormatJSONArray
+	outputFormatCSV
+	outputFormatTSV
+	outputFormatMarkdown
+	outputFormatHTML
+	outputFormatYAML
+	outputFormatRSS
+	outputFormatAtom
+	outputFormatICal
+	outputFormatGeoJSON
+	outputFormatSQLDump
+	outputFormatXLSX // Written to xlsxFile instead of stdout
+)
+
+type outputOptions struct {
+	format     outputFormat
+	file       string   // Write to stdout if empty
+	columns    []column // Use the format's default columns if nil
+	jsonIndent bool
+
+	// Of the table cells, no truncation if 0
+	tableMaxWidth int
+	// Show all columns in tabular human-readable output by default
+	// rather than the summary ones only
+	tableDetails bool
+	// Highlight the recent items of tables using terminal colors
+	tableColor bool
+	// Label the columns in English rather than in German
+	english bool
+	// Masks the items rendered if set, see -anonymize
+	anonymizer *anonymizer
+	// Render a QR code per item in HTML, see -html-qr
+	htmlQRCodes bool
+
+	geocoder geocoderConfig
+
+	xlsxFile string
+}
+
+// renderOutput renders the items to the configured output file or to
+// stdout. The output file is replaced atomically.
+func renderOutput(
+	ctx context.Context,
+	l *slog.Logger,
+	items []*item,
+	opts outputOptions,
+) error {
+	return writeOutput(opts.file, func(w io.Writer) error {
+		return render(ctx, l, w, items, opts)
+	})
+}
+
+// isTerminal reports whether f is a terminal rather than e.g. a pipe or
+// a file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// writeOutput calls write with stdout if file is empty, or with the
+// atomically replaced file otherwise.
+func writeOutput(file string, write func(w io.Writer) error) error {
+	if file == "" {
+		return write(os.Stdout)
+	}
+
+	if err := writeFileAtomic(file, outputFilePermissions, write); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+func render(
+	ctx context.Context,
+	l *slog.Logger,
+	w io.Writer,
+	items []*item,
+	opts outputOptions,
+) error {
+	if opts.anonymizer != nil {
+		items = opts.anonymizer.anonymize(items)
+	}
+
+	columns, tableColumns := opts.columns, opts.columns
+	if columns == nil {
+		tableColumns = defaultTableColumns(opts.tableDetails)
+	}
+
+	labeled := func(columns []column) []column {
+		if opts.english {
+			return englishColumns(columns)
+		}
+		return columns
+	}
+
+	switch opts.format {
+	case outputFormatTable:
+		return renderTable(w, items, labeled(tableColumns), opts.tableMaxWidth, opts.tableColor, time.Now())
+	case outputFormatJSON:
+		return renderJSON(w, items, columns, opts.jsonIndent)
+	case outputFormatJSONArray:
+		return renderJSONArray(w, items, columns, opts.jsonIndent)
+	case outputFormatCSV:
+		return renderCSV(w, items, labeled(columnsOrDefault(columns)))
+	case outputFormatTSV:
+		return renderTSV(w, items, labeled(columnsOrDefault(columns)))
+	case outputFormatMarkdown:
+		return renderMarkdown(w, items, labeled(tableColumns))
+	case outputFormatHTML:
+		return renderHTML(w, items, labeled(tableColumns), opts.htmlQRCodes)
+	case outputFormatYAML:
+		return renderYAML(w, items)
+	case outputFormatRSS:
+		return renderRSS(w, items)
+	case outputFormatAtom:
+		return renderAtom(w, items)
+	case outputFormatICal:
+		return renderICal(ctx, l, w, items)
+	case outputFormatGeoJSON:
+		return renderGeoJSON(ctx, l, w, items, opts)
+	case outputFormatSQLDump:
+		return renderSQLDump(w, items)
+	case outputFormatXLSX:
+		return renderXLSX(ctx, l, opts.xlsxFile, items, labeled(columnsOrDefault(columns)))
+	}
+
+	return fmt.Errorf("unknown output format %d", opts.format)
+}
+
+func columnsOrDefault(columns []column) []column {
+	if columns == nil {
+		return defaultColumns()
+	}
+	return columns
+}
+
+// newTable builds the table of items. Cells are capped to maxWidth, a
+// maxWidth of 0 disables truncation.
+func newTable(items []*item, columns []column, maxWidth int) table.Writer {
+	capped := func(s string) string {
+		if maxWidth <= 0 {
+			return s
+		}
+		return capstring(s, maxWidth)
+	}
+
+	t := table.NewWriter()
+	t.SetAutoIndex(true)
+	t.SetTitle("Lebensmittelkontrolle")
+	header := make(table.Row, 0, len(columns))
+	for _, c := range columns {
+		header = append(header, c.label)
+	}
+	t.AppendHeader(header)
+	for _, itm := range items {
+		row := make(table.Row, 0, len(columns))
+		for _, c := range columns {
+			row = append(row, capped(c.text(itm)))
+		}
+		t.AppendRow(row)
+	}
+
+	return t
+}
+
+// renderTable prints the items as a table, the cells truncated to
+// maxWidth characters unless it's 0. If color is set, the items
+// published since the day before now are highlighted and the vanished
+// ones dimmed.
+func renderTable(w io.Writer, items []*item, columns []column, maxWidth int, color bool, now time.Time) error {
+	t := newTable(items, columns, maxWidth)
+	if color {
+		recent := daysAgo(now, 1)
+		t.Style().Color.Header = text.Colors{text.Bold}
+		t.SetRowPainter(table.RowPainterWithAttributes(func(_ table.Row, attr table.RowAttributes) text.Colors {
+			switch itm := items[attr.Number-1]; {
+			case itm.Vanished:
+				return text.Colors{text.Faint}
+			case !itm.PublishedAt.IsZero() && !itm.PublishedAt.Before(recent):
+				return text.Colors{text.Bold, text.FgYellow}
+			}
+			return nil
+		}))
+	}
+	if _, err := fmt.Fprintln(w, t.Render()); err != nil {
+		return fmt.Errorf("failed to print: %w", err)
+	}
+
+	return nil
+}
+
+func renderMarkdown(w io.Writer, items []*item, columns []column) error {
+	// Markdown cells may be long, don't truncate them.
+	// RenderMarkdown escapes pipes and newlines on its own.
+	t := newTable(items, markdownColumns(columns), 0)
+	if _, err := fmt.Fprintln(w, t.RenderMarkdown()); err != nil {
+		return fmt.Errorf("failed to Markdown-print: %w", err)
+	}
+
+	return nil
+}
+
+// markdownColumns returns copies of the columns with the map URLs
+// rendered as links.
+func markdownColumns(columns []column) []column {
+	md := make([]column, 0, len(columns))
+	for _, c := range columns {
+		if c.name == columnMapURL {
+			c.text = func(itm *item) string {
+				if itm.MapURL == "" {
+					return ""
+				}
+				return "<" + itm.MapURL + ">"
+			}
+		}
+		md = append(md, c)
+	}
+	return md
+}
+
+func newJSONEncoder(w io.Writer, indent bool) *json.Encoder {
+	enc := json.NewEncoder(w)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
+
+func renderJSON(w io.Writer, items []*item, columns []column, indent bool) error {
+	enc := newJSONEncoder(w, indent)
+	for _, v := range jsonValues(items, columns) {
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to JSON-print: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func renderJSONArray(w io.Writer, items []*item, columns []column, indent bool) error {
+	// Never nil, print an empty array instead of null
+	values := jsonValues(items, columns)
+
+	if err := newJSONEncoder(w, indent).Encode(values); err != nil {
+		return fmt.Errorf("failed to JSON-print: %w", err)
+	}
+
+	return nil
+}
+
+// jsonStreamWriter writes values one by one, either newline-delimited
+// or as the elements of a single array.
+type jsonStreamWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	array bool
+	n     int // Number of values written
+}
+
+func newJSONStreamWriter(w io.Writer, array, indent bool) *jsonStreamWriter {
+	return &jsonStreamWriter{
+		w:     w,
+		enc:   newJSONEncoder(w, indent),
+		array: array,
+	}
+}
+
+func (jw *jsonStreamWriter) write(itm *item) error {
+	if jw.array {
+		sep := ","
+		if jw.n == 0 {
+			sep = "["
+		}
+		if _, err := io.WriteString(jw.w, sep); err != nil {
+			return fmt.Errorf("failed to JSON-print: %w", err)
+		}
+	}
+	jw.n++
+
+	if err := jw.enc.Encode(itm); err != nil {
+		return fmt.Errorf("failed to JSON-print: %w", err)
+	}
+
+	return nil
+}
+
+func (jw *jsonStreamWriter) close() error {
+	if !jw.array {
+		return nil
+	}
+
+	end := "]\n"
+	if jw.n == 0 {
+		end = "[]\n"
+	}
+	if _, err := io.WriteString(jw.w, end); err != nil {
+		return fmt.Errorf("failed to JSON-print: %w", err)
+	}
+
+	return nil
+}
+
+func renderCSV(w io.Writer, items []*item, columns []column) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true // As mandated by RFC 4180
+
+	if err := cw.Write(columnLabels(columns)); err != nil {
+		return fmt.Errorf("failed to CSV-print header: %w", err)
+	}
+	for _, itm := range items {
+		if err := cw.Write(columnTexts(itm, columns)); err != nil {
+			return fmt.Errorf("failed to CSV-print: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV: %w", err)
+	}
+
+	return nil
+}
+
+//nolint:gochecknoglobals // It's a constant replacer
+var tsvFieldSanitizer = strings.NewReplacer(
+	"\r\n", " ",
+	"\r", " ",
+	"\n", " ",
+	"\t", " ",
+)
+
+// renderTSV prints the items as tab-separated values. Unlike CSV, fields
+// are never quoted, embedded tabs and newlines, which would misalign the
+// columns, are replaced by spaces instead.
+func renderTSV(w io.Writer, items []*item, columns []column) error {
+	bw := bufio.NewWriter(w)
+	record := func(fields []string) {
+		for i, f := range fields {
+			fields[i] = tsvFieldSanitizer.Replace(f)
+		}
+		bw.WriteString(strings.Join(fields, "\t") + "\n") //nolint:errcheck // Reported by Flush
+	}
+
+	record(columnLabels(columns))
+	for _, itm := range items {
+		record(columnTexts(itm, columns))
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to TSV-print: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	feedTitle       = "Lebensmittelkontrolle"
+	feedDescription = "Veröffentlichungen der Lebensmittelkontrolle Baden-Württemberg"
+	feedLanguage    = "de-DE"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSS(w io.Writer, items []*item) error {
+	ritems := make([]rssItem, 0, len(items))
+	for _, itm := range items {
+		hash := itemHash(itm)
+
+		ritm := rssItem{
+			Title:       itemTitle(itm),
+			Link:        lmkURL,
+			Description: strings.Join([]string{itm.Reason, itm.Address}, "\n\n"),
+			GUID: rssGUID{
+				IsPermaLink: false,
+				Value:       hash,
+			},
+		}
+		// Items with an unparsed date are emitted without a pubDate
+		if !itm.PublishedAt.IsZero() {
+			ritm.PubDate = itm.PublishedAt.Format(time.RFC1123Z)
+		}
+		ritems = append(ritems, ritm)
+	}
+
+	return writeXML(w, rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         feedTitle,
+			Link:          lmkURL,
+			Description:   feedDescription,
+			Language:      feedLanguage,
+			LastBuildDate: time.Now().Format(time.RFC1123Z),
+			Items:         ritems,
+		},
+	})
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to XML-print header: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to XML-print: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to close XML encoder: %w", err)
+	}
+
+	// The encoder does not terminate its output with a newline
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to XML-print: %w", err)
+	}
+
+	return nil
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Author  atomPerson `xml:"author"`
+	Link    atomLink   `xml:"link"`
+	Content atomText   `xml:"content"`
+}
+
+func renderAtom(w io.Writer, items []*item) error {
+	// Atom requires an updated timestamp, items without any date get the
+	// feed's, the newest of the items, so rendering the same items always
+	// gives the same feed
+	var feedUpdated time.Time
+	for _, itm := range items {
+		if updated := atomUpdated(itm); updated.After(feedUpdated) {
+			feedUpdated = updated
+		}
+	}
+	if feedUpdated.IsZero() {
+		// None is dated, e.g. there are no items at all
+		feedUpdated = time.Now()
+	}
+
+	entries := make([]atomEntry, 0, len(items))
+	for _, itm := range items {
+		hash := itemHash(itm)
+
+		updated := atomUpdated(itm)
+		if updated.IsZero() {
+			updated = feedUpdated
+		}
+
+		entries = append(entries, atomEntry{
+			ID:      "urn:sha256:" + hash,
+			Title:   itemTitle(itm),
+			Updated: updated.Format(time.RFC3339),
+			Author:  atomPerson{Name: itm.Authority},
+			Link:    atomLink{Href: lmkURL},
+			Content: atomText{
+				Type: "text",
+				Value: strings.Join([]string{
+					labelAuthority + ": " + itm.Authority,
+					labelReason + ": " + itm.Reason,
+					labelLegalBasis + ": " + itm.LegalBasis,
+				}, "\n"),
+			},
+		})
+	}
+
+	return writeXML(w, atomFeed{
+		ID:      lmkURL,
+		Title:   feedTitle,
+		Updated: feedUpdated.Format(time.RFC3339),
+		Link:    atomLink{Href: lmkURL},
+		Entries: entries,
+	})
+}
+
+// atomUpdated returns the time the entry of itm was updated at: when it
+// was published, falling back to the time of the inspection and lastly to
+// when it was first stored. It's zero if none is known.
+func atomUpdated(itm *item) time.Time {
+	for _, t := range []time.Time{itm.PublishedAt, itm.FoundAt, itm.FirstSeen} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
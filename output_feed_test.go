@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+// xmlNode is any XML element along with its children.
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []xmlNode  `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+func (n xmlNode) attr(name string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+func (n xmlNode) children(name string) []xmlNode {
+	var cs []xmlNode
+	for _, c := range n.Children {
+		if c.XMLName.Space == atomNS && c.XMLName.Local == name {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// atomElement describes the children an Atom element may have, see the
+// RELAX NG schema of RFC 4287, appendix B.
+type atomElement struct {
+	// Exactly one each
+	required []string
+	// At most one each
+	optional []string
+	// Any number each
+	repeated []string
+}
+
+//nolint:gochecknoglobals // Read-only lookup table
+var atomSchema = map[string]atomElement{
+	"feed": {
+		required: []string{"id", "title", "updated"},
+		optional: []string{"generator", "icon", "logo", "rights", "subtitle"},
+		repeated: []string{"author", "category", "contributor", "link", "entry"},
+	},
+	"entry": {
+		required: []string{"id", "title", "updated"},
+		optional: []string{"content", "published", "rights", "source", "summary"},
+		repeated: []string{"author", "category", "contributor", "link"},
+	},
+	"author": {
+		required: []string{"name"},
+		optional: []string{"email", "uri"},
+	},
+	"id":      {},
+	"title":   {},
+	"updated": {},
+	"link":    {},
+	"content": {},
+	"name":    {},
+}
+
+// validateAtom reports where n violates RFC 4287, the elements lmk
+// doesn't render are only checked for being allowed.
+func validateAtom(t *testing.T, path string, n xmlNode) {
+	t.Helper()
+
+	if n.XMLName.Space != atomNS {
+		t.Errorf("%s: element in namespace %q, want %q", path, n.XMLName.Space, atomNS)
+		return
+	}
+	el, ok := atomSchema[n.XMLName.Local]
+	if !ok {
+		return
+	}
+
+	for _, c := range n.Children {
+		name := c.XMLName.Local
+		if c.XMLName.Space == atomNS &&
+			!slices.Contains(el.required, name) &&
+			!slices.Contains(el.optional, name) &&
+			!slices.Contains(el.repeated, name) {
+			t.Errorf("%s: unexpected child %s", path, name)
+		}
+	}
+	for _, name := range el.required {
+		if got := len(n.children(name)); got != 1 {
+			t.Errorf("%s: got %d %s, want exactly one", path, got, name)
+		}
+	}
+	for _, name := range el.optional {
+		if got := len(n.children(name)); got > 1 {
+			t.Errorf("%s: got %d %s, want at most one", path, got, name)
+		}
+	}
+
+	switch n.XMLName.Local {
+	case "id":
+		if u, err := url.Parse(n.Text); err != nil || !u.IsAbs() {
+			t.Errorf("%s: %q is no absolute IRI", path, n.Text)
+		}
+	case "updated":
+		if _, err := time.Parse(time.RFC3339, n.Text); err != nil {
+			t.Errorf("%s: %q is no RFC 3339 date: %v", path, n.Text, err)
+		}
+	case "link":
+		href, ok := n.attr("href")
+		if u, err := url.Parse(href); !ok || err != nil {
+			t.Errorf("%s: %q is no IRI reference", path, href)
+		} else if !u.IsAbs() {
+			t.Errorf("%s: relative %q without a base", path, href)
+		}
+	case "title", "content":
+		if typ, ok := n.attr("type"); ok && !slices.Contains([]string{"text", "html", "xhtml"}, typ) {
+			t.Errorf("%s: unexpected type %q", path, typ)
+		}
+	}
+
+	for i, c := range n.Children {
+		validateAtom(t, path+"/"+c.XMLName.Local+"["+strconv.Itoa(i)+"]", c)
+	}
+}
+
+func TestRenderAtom(t *testing.T) {
+	t.Parallel()
+
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	items := []*item{
+		{
+			State:       "bw",
+			Authority:   "Landratsamt Esslingen",
+			PublishedAt: published,
+			FoundAt:     published.AddDate(0, 0, -7),
+			Name:        "Bäckerei Müller",
+			Address:     "Hauptstraße 1, 73728 Esslingen am Neckar",
+			Reason:      "Mäusekot <in> der Backstube & mehr",
+			LegalBasis:  "§ 11 LFGB",
+		},
+		{
+			// The publication date couldn't be parsed
+			State:      "bw",
+			Authority:  "Stadt Stuttgart",
+			FoundAt:    published.AddDate(0, 0, -1),
+			Name:       "Metzgerei Schmid",
+			Address:    "Königstraße 10, 70173 Stuttgart",
+			Reason:     "Kühlkette unterbrochen",
+			LegalBasis: "§ 11 LFGB",
+		},
+		{
+			State:      "bw",
+			Authority:  "Stadt Ulm",
+			FirstSeen:  published.AddDate(0, 0, 2),
+			Name:       "Imbiss Ulm",
+			Reason:     "Reinigungsmängel",
+			LegalBasis: "§ 40 Abs. 1a LFGB",
+		},
+		{
+			State:      "bw",
+			Authority:  "Stadt Ulm",
+			Name:       "Café Ulm",
+			Reason:     "Schimmel",
+			LegalBasis: "§ 40 Abs. 1a LFGB",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := renderAtom(&buf, items); err != nil {
+		t.Fatalf("failed to render Atom feed: %v", err)
+	}
+
+	var feed xmlNode
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatalf("failed to parse Atom feed: %v", err)
+	}
+	if feed.XMLName.Local != "feed" {
+		t.Fatalf("got root element %s, want feed", feed.XMLName.Local)
+	}
+	validateAtom(t, "/feed", feed)
+
+	entries := feed.children("entry")
+	if len(entries) != len(items) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(items))
+	}
+	ids := map[string]bool{}
+	for i, e := range entries {
+		id := e.children("id")[0].Text
+		if ids[id] {
+			t.Errorf("entry %d: duplicate id %q", i, id)
+		}
+		ids[id] = true
+
+		// The feed has no author, so each entry needs one
+		if len(e.children("author")) == 0 {
+			t.Errorf("entry %d: no author", i)
+		}
+	}
+
+	newest := published.AddDate(0, 0, 2).Format(time.RFC3339)
+	if got := feed.children("updated")[0].Text; got != newest {
+		t.Errorf("got feed updated %s, want the newest date %s", got, newest)
+	}
+	for i, want := range []string{
+		published.Format(time.RFC3339),
+		published.AddDate(0, 0, -1).Format(time.RFC3339),
+		newest,
+		newest,
+	} {
+		if got := entries[i].children("updated")[0].Text; got != want {
+			t.Errorf("entry %d: got updated %s, want %s", i, got, want)
+		}
+	}
+
+	// Nothing depends on the current time
+	var again bytes.Buffer
+	if err := renderAtom(&again, items); err != nil {
+		t.Fatalf("failed to render Atom feed: %v", err)
+	}
+	if !bytes.Equal(again.Bytes(), buf.Bytes()) {
+		t.Errorf("got different feeds rendering the same items")
+	}
+}
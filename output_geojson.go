@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   *geoJSONGeometry  `json:"geometry"` // null if the address failed to geocode
+	Properties geoJSONProperties `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` // Longitude, latitude
+}
+
+type geoJSONProperties struct {
+	Name      string `json:"name"`
+	Authority string `json:"authority"`
+	Reason    string `json:"reason"`
+	Vanished  bool   `json:"vanished,omitempty"`
+}
+
+func renderGeoJSON(
+	ctx context.Context,
+	l *slog.Logger,
+	w io.Writer,
+	items []*item,
+	opts outputOptions,
+) error {
+	g, err := openGeocoder(l, opts.geocoder)
+	if err != nil {
+		return err
+	}
+
+	coords := geocodeItems(ctx, l, g, items, opts.geocoder.concurrency)
+
+	if err := g.Save(); err != nil {
+		// Not fatal, we'll just have to geocode again next time
+		l.WarnContext(ctx, err.Error())
+	}
+
+	features := make([]geoJSONFeature, 0, len(items))
+	for i, itm := range items {
+		f := geoJSONFeature{
+			Type: "Feature",
+			Properties: geoJSONProperties{
+				Name:      itm.Name,
+				Authority: itm.Authority,
+				Reason:    itm.Reason,
+				Vanished:  itm.Vanished,
+			},
+		}
+		if c := coords[i]; c != nil {
+			f.Geometry = &geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{c.Lon, c.Lat},
+			}
+		}
+		features = append(features, f)
+	}
+
+	if err := newJSONEncoder(w, opts.jsonIndent).Encode(geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+	}); err != nil {
+		return fmt.Errorf("failed to GeoJSON-print: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	// Of the QR codes, in pixels, see -html-qr
+	htmlQRCodeSize = 128
+
+	htmlTemplate = `<!DOCTYPE html>
+<html lang="de">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.5em; text-align: left; vertical-align: top; }
+th { background: #eee; }
+tr:nth-child(even) td { background: #fafafa; }
+td { white-space: pre-line; }
+td.qr img { display: block; width: 6em; height: 6em; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<table>
+<thead>
+<tr>
+<th>#</th>
+{{- range .Labels }}
+<th>{{ . }}</th>
+{{- end }}
+{{- if .QRCodes }}
+<th>QR</th>
+{{- end }}
+</tr>
+</thead>
+<tbody>
+{{- range $i, $row := .Rows }}
+<tr>
+<td>{{ inc $i }}</td>
+{{- range $row.Cells }}
+<td>{{ if .Link }}<a href="{{ .Link }}">{{ .Text }}</a>{{ else }}{{ .Text }}{{ end }}</td>
+{{- end }}
+{{- if $.QRCodes }}
+<td class="qr">{{ if $row.QRCode }}<img src="{{ $row.QRCode }}" alt="QR">{{ end }}</td>
+{{- end }}
+</tr>
+{{- end }}
+</tbody>
+</table>
+</body>
+</html>
+`
+)
+
+type htmlCell struct {
+	Text string
+	Link string
+}
+
+type htmlRow struct {
+	Cells []htmlCell
+	// Data URI of the PNG, empty unless QR codes are rendered
+	QRCode template.URL
+}
+
+// renderHTML prints the items as an HTML document. If qrCodes is set,
+// each item is followed by a QR code of its map URL, or of its source's
+// page if it has no address.
+func renderHTML(w io.Writer, items []*item, columns []column, qrCodes bool) error {
+	tpl, err := template.New("html").Funcs(template.FuncMap{
+		"inc": func(i int) int { return i + 1 },
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+
+	rows := make([]htmlRow, 0, len(items))
+	for _, itm := range items {
+		var row htmlRow
+		if qrCodes {
+			uri, err := qrCodeDataURI(itemQRCodeURL(itm))
+			if err != nil {
+				return err
+			}
+			row.QRCode = uri
+		}
+		for _, c := range columns {
+			cell := htmlCell{
+				Text: c.text(itm),
+			}
+			switch c.name {
+			case columnAddress:
+				// Rather than of the text, which may be anonymized, the
+				// map URL of anonymized items is cleared
+				cell.Link = itm.MapURL
+			case columnMapURL:
+				cell.Link = cell.Text
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	if err := tpl.Execute(w, struct {
+		Title   string
+		Labels  []string
+		Rows    []htmlRow
+		QRCodes bool
+	}{
+		Title:   "Lebensmittelkontrolle",
+		Labels:  columnLabels(columns),
+		Rows:    rows,
+		QRCodes: qrCodes,
+	}); err != nil {
+		return fmt.Errorf("failed to HTML-print: %w", err)
+	}
+
+	return nil
+}
+
+// itemQRCodeURL returns the URL encoded by the QR code of itm, its map
+// URL or the page of its source if it has no address.
+func itemQRCodeURL(itm *item) string {
+	if itm.MapURL != "" {
+		return itm.MapURL
+	}
+	if src, err := lookupSource(itm.State); err == nil {
+		return src.url
+	}
+	return lmkURL
+}
+
+// qrCodeDataURI returns the data URI of a PNG QR code encoding content.
+// The same content always results in the same URI.
+func qrCodeDataURI(content string) (template.URL, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, htmlQRCodeSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)), nil //nolint:gosec // Of the PNG only
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRenderHTMLAnonymizedAddress(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+
+	itm := &item{
+		State:     "bw",
+		Authority: "Stadt Stuttgart",
+		Name:      "Metzgerei Schmid",
+		Address:   "Königstraße 10, 70173 Stuttgart",
+	}
+	setAddressParts(itm)
+
+	for _, tc := range []struct {
+		name       string
+		anonymizer *anonymizer
+		wantLink   bool
+	}{
+		{"plain", nil, true},
+		{"anonymized", &anonymizer{secret: "secret"}, false},
+	} {
+		var buf bytes.Buffer
+		if err := render(ctx, l, &buf, []*item{itm}, outputOptions{
+			format:       outputFormatHTML,
+			tableDetails: true,
+			anonymizer:   tc.anonymizer,
+		}); err != nil {
+			t.Fatalf("%s: failed to render HTML: %v", tc.name, err)
+		}
+
+		out := buf.String()
+		if got := strings.Contains(out, mapSearchURL); got != tc.wantLink {
+			t.Errorf("%s: got map link %t, want %t", tc.name, got, tc.wantLink)
+		}
+		if tc.anonymizer != nil && strings.Contains(out, "Königstraße") {
+			t.Errorf("%s: got the address in the output", tc.name)
+		}
+	}
+}
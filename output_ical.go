@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icalDateFormat     = "20060102"
+	icalDateTimeFormat = "20060102T150405Z"
+
+	// Lines longer than this many octets must be folded, see RFC 5545 section 3.1
+	icalMaxLineLength = 75
+)
+
+//nolint:gochecknoglobals // It's a constant replacer
+var icalTextEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// icalFold folds a content line as described in RFC 5545 section 3.1
+// without splitting multi-byte characters.
+func icalFold(line string) string {
+	var b strings.Builder
+	var n int
+	for _, r := range line {
+		rl := utf8.RuneLen(r)
+		if n+rl > icalMaxLineLength {
+			b.WriteString("\r\n ")
+			n = 1 // The leading space counts towards the line length
+		}
+		b.WriteRune(r)
+		n += rl
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func renderICal(ctx context.Context, l *slog.Logger, w io.Writer, items []*item) error {
+	dtstamp := time.Now().UTC().Format(icalDateTimeFormat)
+
+	var b strings.Builder
+	writeLine := func(name, value string) {
+		b.WriteString(icalFold(name + ":" + value))
+	}
+
+	writeLine("BEGIN", "VCALENDAR")
+	writeLine("VERSION", "2.0")
+	writeLine("PRODID", "-//leonklingele//lmk//DE")
+	writeLine("CALSCALE", "GREGORIAN")
+	writeLine("X-WR-CALNAME", feedTitle)
+	for _, itm := range items {
+		if itm.FoundAt.IsZero() {
+			l.WarnContext(
+				ctx,
+				"skipping item without parsed found at date",
+				"item", fmt.Sprintf("%+v", itm),
+			)
+			continue
+		}
+
+		hash := itemHash(itm)
+
+		// All-day events, the end is exclusive
+		end := itm.FoundAt
+		if !itm.FoundAtEnd.IsZero() {
+			end = itm.FoundAtEnd
+		}
+
+		writeLine("BEGIN", "VEVENT")
+		writeLine("UID", hash+"@lmk")
+		writeLine("DTSTAMP", dtstamp)
+		writeLine("DTSTART;VALUE=DATE", itm.FoundAt.Format(icalDateFormat))
+		writeLine("DTEND;VALUE=DATE", end.AddDate(0, 0, 1).Format(icalDateFormat))
+		writeLine("SUMMARY", icalTextEscaper.Replace(itemTitle(itm)))
+		writeLine("LOCATION", icalTextEscaper.Replace(itm.Address))
+		writeLine("DESCRIPTION", icalTextEscaper.Replace(itm.Reason))
+		writeLine("END", "VEVENT")
+	}
+	writeLine("END", "VCALENDAR")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to iCal-print: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlQuote returns s as an SQL string literal.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func sqlQuoteTime(t time.Time) string {
+	return sqlQuote(t.Format(sqliteTimeFormat))
+}
+
+// sqlQuoteOptionalTime is like sqlQuoteTime but returns null for the
+// zero time.
+func sqlQuoteOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return "null"
+	}
+	return sqlQuoteTime(t)
+}
+
+// sqlOptionalFloat returns v as an SQL number literal, or null for zero.
+func sqlOptionalFloat(v float64) string {
+	if v == 0 {
+		return "null"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// renderSQLDump prints the items as SQL statements which can be
+// replayed into an empty or existing database. Items already present
+// are skipped thanks to the unique item hash.
+func renderSQLDump(w io.Writer, items []*item) error {
+	var b strings.Builder
+	b.WriteString("begin;\n")
+	b.WriteString(strings.TrimSpace(sqliteCreateSchemaStmt))
+	b.WriteString("\n")
+	for _, itm := range items {
+		hash := itemHash(itm)
+
+		b.WriteString("insert into items (hash, authority, published_at, found_at, name, address, reason, legal_basis, info, state, first_seen, last_seen, published_at_end, found_at_end, latitude, longitude, street, postal_code, city, authority_normalized, deleted_at) values (")
+		b.WriteString(strings.Join([]string{
+			sqlQuote(hash),
+			sqlQuote(itm.Authority),
+			sqlQuoteTime(itm.PublishedAt),
+			sqlQuoteTime(itm.FoundAt),
+			sqlQuote(itm.Name),
+			sqlQuote(itm.Address),
+			sqlQuote(itm.Reason),
+			sqlQuote(itm.LegalBasis),
+			sqlQuote(itm.Info),
+			sqlQuote(itm.State),
+			sqlQuoteOptionalTime(itm.FirstSeen),
+			sqlQuoteOptionalTime(itm.LastSeen),
+			sqlQuoteOptionalTime(itm.PublishedAtEnd),
+			sqlQuoteOptionalTime(itm.FoundAtEnd),
+			sqlOptionalFloat(itm.Latitude),
+			sqlOptionalFloat(itm.Longitude),
+			sqlQuote(itm.Street),
+			sqlQuote(itm.PostalCode),
+			sqlQuote(itm.City),
+			sqlQuote(itm.AuthorityNormalized),
+			sqlQuoteOptionalTime(itm.DeletedAt),
+		}, ", "))
+		b.WriteString(") on conflict (hash) do nothing;\n")
+	}
+	b.WriteString("commit;\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to SQL-print: %w", err)
+	}
+
+	return nil
+}
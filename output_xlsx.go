@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	xlsxSheetName      = "Lebensmittelkontrolle"
+	xlsxDateFormat     = "dd.mm.yyyy"
+	xlsxMaxColumnWidth = 80
+	xlsxColumnPadding  = 2
+)
+
+// renderXLSX writes the items to an Excel workbook at path.
+func renderXLSX(
+	ctx context.Context,
+	l *slog.Logger,
+	path string,
+	items []*item,
+	columns []column,
+) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close workbook: %w", err).Error())
+		}
+	}()
+
+	if err := f.SetSheetName(f.GetSheetName(0), xlsxSheetName); err != nil {
+		return fmt.Errorf("failed to rename sheet: %w", err)
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+		},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Pattern: 1,
+			Color:   []string{"#DDDDDD"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create header style: %w", err)
+	}
+	dateFormat := xlsxDateFormat
+	dateStyle, err := f.NewStyle(&excelize.Style{
+		CustomNumFmt: &dateFormat,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create date style: %w", err)
+	}
+
+	widths := make([]int, len(columns))
+	setCell := func(col, row int, v any, style int) error {
+		cell, err := excelize.CoordinatesToCellName(col+1, row+1)
+		if err != nil {
+			return fmt.Errorf("failed to get cell name: %w", err)
+		}
+		if err := f.SetCellValue(xlsxSheetName, cell, v); err != nil {
+			return fmt.Errorf("failed to set cell %s: %w", cell, err)
+		}
+		if style != 0 {
+			if err := f.SetCellStyle(xlsxSheetName, cell, cell, style); err != nil {
+				return fmt.Errorf("failed to set style of cell %s: %w", cell, err)
+			}
+		}
+		return nil
+	}
+	fitColumn := func(col int, s string) {
+		widths[col] = max(widths[col], utf8.RuneCountInString(s))
+	}
+
+	for col, c := range columns {
+		if err := setCell(col, 0, c.label, headerStyle); err != nil {
+			return err
+		}
+		fitColumn(col, c.label)
+	}
+
+	for i, itm := range items {
+		row := i + 1
+		for col, c := range columns {
+			// Write parsed dates as real date cells, keep the
+			// scraped text if they couldn't be parsed.
+			var v any = c.text(itm)
+			var style int
+			if t, ok := c.value(itm).(*time.Time); ok && t != nil {
+				v, style = *t, dateStyle
+			}
+			if err := setCell(col, row, v, style); err != nil {
+				return err
+			}
+			fitColumn(col, c.text(itm))
+		}
+	}
+
+	for col, width := range widths {
+		name, err := excelize.ColumnNumberToName(col + 1)
+		if err != nil {
+			return fmt.Errorf("failed to get column name: %w", err)
+		}
+		if err := f.SetColWidth(
+			xlsxSheetName,
+			name,
+			name,
+			float64(min(width+xlsxColumnPadding, xlsxMaxColumnWidth)),
+		); err != nil {
+			return fmt.Errorf("failed to set width of column %s: %w", name, err)
+		}
+	}
+
+	// Freeze the header row
+	if err := f.SetPanes(xlsxSheetName, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return fmt.Errorf("failed to freeze header: %w", err)
+	}
+
+	if err := writeFileAtomic(path, outputFilePermissions, func(w io.Writer) error {
+		if _, err := f.WriteTo(w); err != nil {
+			return fmt.Errorf("failed to write workbook: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save workbook: %w", err)
+	}
+
+	return nil
+}
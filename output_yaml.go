@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlItem mirrors the JSON representation of an item. The helper
+// PublishedAtStr / FoundAtStr fields are excluded just like in JSON,
+// the parsed dates are emitted in timeFormat instead.
+type yamlItem struct {
+	Authority   string `yaml:"authority"`
+	PublishedAt string `yaml:"published_at"`
+	FoundAt     string `yaml:"found_at"`
+	// Empty for single dates
+	PublishedAtEnd string  `yaml:"published_at_end,omitempty"`
+	FoundAtEnd     string  `yaml:"found_at_end,omitempty"`
+	Name           string  `yaml:"name"`
+	Address        string  `yaml:"address"`
+	Reason         string  `yaml:"reason"`
+	LegalBasis     string  `yaml:"legal_basis"`
+	Info           string  `yaml:"info"`
+	Street         string  `yaml:"street"`
+	PostalCode     string  `yaml:"postal_code"`
+	City           string  `yaml:"city"`
+	State          string  `yaml:"state"`
+	Latitude       float64 `yaml:"latitude,omitempty"`
+	Longitude      float64 `yaml:"longitude,omitempty"`
+	MapURL         string  `yaml:"map_url"`
+	FirstSeen      string  `yaml:"first_seen,omitempty"`
+	LastSeen       string  `yaml:"last_seen,omitempty"`
+	Vanished       bool    `yaml:"vanished,omitempty"`
+
+	AuthorityNormalized string `yaml:"authority_normalized"`
+	DeletedAt           string `yaml:"deleted_at,omitempty"`
+}
+
+func renderYAML(w io.Writer, items []*item) error {
+	yitems := make([]yamlItem, 0, len(items))
+	for _, itm := range items {
+		yitems = append(yitems, yamlItem{
+			Authority:      itm.Authority,
+			PublishedAt:    formatDate(itm.PublishedAt),
+			FoundAt:        formatDate(itm.FoundAt),
+			PublishedAtEnd: formatDate(itm.PublishedAtEnd),
+			FoundAtEnd:     formatDate(itm.FoundAtEnd),
+			Name:           itm.Name,
+			Address:        itm.Address,
+			Reason:         itm.Reason,
+			LegalBasis:     itm.LegalBasis,
+			Info:           itm.Info,
+			Street:         itm.Street,
+			PostalCode:     itm.PostalCode,
+			City:           itm.City,
+			State:          itm.State,
+			Latitude:       itm.Latitude,
+			Longitude:      itm.Longitude,
+			MapURL:         itm.MapURL,
+			FirstSeen:      formatTimestamp(itm.FirstSeen),
+			LastSeen:       formatTimestamp(itm.LastSeen),
+			Vanished:       itm.Vanished,
+
+			AuthorityNormalized: itm.AuthorityNormalized,
+			DeletedAt:           formatTimestamp(itm.DeletedAt),
+		})
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2) //nolint:mnd // Two spaces are the YAML convention
+	if err := enc.Encode(yitems); err != nil {
+		return fmt.Errorf("failed to YAML-print: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to close YAML encoder: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+func parseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q, expected an absolute http(s) or socks5 URL", s)
+	}
+	return u, nil
+}
+
+// newProxyTransport returns a transport using the proxy configured by
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY. A non-nil proxy replaces the
+// first two but hosts listed in NO_PROXY are still connected to
+// directly.
+func newProxyTransport(proxy *url.URL) *http.Transport {
+	cfg := httpproxy.FromEnvironment()
+	if proxy != nil {
+		cfg.HTTPProxy = proxy.String()
+		cfg.HTTPSProxy = proxy.String()
+	}
+	proxyFunc := cfg.ProxyFunc()
+
+	t := &http.Transport{}
+	if dt, ok := http.DefaultTransport.(*http.Transport); ok {
+		// Keep the default dial and TLS timeouts
+		t = dt.Clone()
+	}
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+
+	return t
+}
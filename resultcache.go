@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	resultCacheFileName        = "result-cache.gob"
+	resultCacheFilePermissions = 0o600
+
+	// Long enough to print several output formats in a row, see -cache-ttl
+	defaultResultCacheTTL = 5 * time.Minute
+)
+
+// scrapeResult is the result of the last successful scrape, cached so
+// runs shortly after don't fetch the page again. gob-encoded as the
+// date strings of the items aren't part of their JSON encoding.
+type scrapeResult struct {
+	// Of the scrape, see resultCacheKey, results of others don't match
+	Key       string
+	ScrapedAt time.Time
+	Items     []*item
+}
+
+// resultCacheKey returns the hex-encoded SHA-256 hash of everything the
+// items scraped from src as configured by opts depend on: the build,
+// whose parsing may differ, the page, and the options of parsing it. The
+// values are hashed like itemHash does.
+func resultCacheKey(src *source, opts loadOptions) string {
+	vs := []string{
+		buildVersion(),
+		src.state,
+		opts.url,
+		strconv.FormatBool(opts.lenient),
+	}
+	for _, c := range src.columns {
+		vs = append(vs, c.label, strconv.Itoa(int(c.field)))
+	}
+	for _, raw := range slices.Sorted(maps.Keys(src.authorities)) {
+		vs = append(vs, raw, src.authorities[raw])
+	}
+
+	h := sha256.New()
+	for _, v := range vs {
+		h.Write([]byte(strconv.Itoa(len(v)) + ":" + v + ";"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadScrapeResult returns the items saved by saveScrapeResult if they
+// were scraped with the given key, see resultCacheKey, within ttl before
+// now, nil otherwise, e.g. if the file doesn't exist yet.
+func loadScrapeResult(path, key string, ttl time.Duration, now time.Time) ([]*item, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result cache: %w", err)
+	}
+
+	var r scrapeResult
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to decode result cache: %w", err)
+	}
+	if r.Key != key || now.Sub(r.ScrapedAt) > ttl || r.ScrapedAt.After(now) {
+		return nil, nil
+	}
+
+	return r.Items, nil
+}
+
+func saveScrapeResult(path string, r scrapeResult) error {
+	if err := writeFileAtomic(path, resultCacheFilePermissions, func(w io.Writer) error {
+		if err := gob.NewEncoder(w).Encode(r); err != nil {
+			return fmt.Errorf("failed to encode result cache: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to save result cache: %w", err)
+	}
+
+	return nil
+}
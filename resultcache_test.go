@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultCacheKey(t *testing.T) {
+	t.Parallel()
+
+	src, err := lookupSource("bw")
+	if err != nil {
+		t.Fatalf("failed to look up source: %v", err)
+	}
+	opts := loadOptions{url: src.url}
+	key := resultCacheKey(src, opts)
+
+	if got := resultCacheKey(src, opts); got != key {
+		t.Errorf("got key %s, want the same %s for the same scrape", got, key)
+	}
+
+	labels := make([]string, len(src.columns))
+	for i, c := range src.columns {
+		labels[i] = c.label
+	}
+	labels[0] = "Amt"
+	relabeled, err := src.withLabels(labels)
+	if err != nil {
+		t.Fatalf("failed to set labels: %v", err)
+	}
+	otherSource := *src
+	otherSource.state = "by"
+
+	for name, other := range map[string]string{
+		"source":      resultCacheKey(&otherSource, opts),
+		"url":         resultCacheKey(src, loadOptions{url: src.url + "?page=2"}),
+		"lenient":     resultCacheKey(src, loadOptions{url: src.url, lenient: true}),
+		"labels":      resultCacheKey(relabeled, opts),
+		"authorities": resultCacheKey(src.withAuthorities(newAuthorityNormalizer(map[string]string{"LRA X": "Landratsamt X"})), opts),
+	} {
+		if other == key {
+			t.Errorf("got the same key for another %s", name)
+		}
+	}
+}
+
+func TestLoadScrapeResult(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), resultCacheFileName)
+	now := time.Now()
+	items := []*item{{State: "bw", Name: "Bäckerei Müller"}}
+	if err := saveScrapeResult(path, scrapeResult{Key: "a", ScrapedAt: now, Items: items}); err != nil {
+		t.Fatalf("failed to save result: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		key  string
+		at   time.Time
+		want int
+	}{
+		{"same key", "a", now.Add(time.Minute), 1},
+		{"other key", "b", now.Add(time.Minute), 0},
+		{"expired", "a", now.Add(time.Hour), 0},
+	} {
+		got, err := loadScrapeResult(path, tc.key, defaultResultCacheTTL, tc.at)
+		if err != nil {
+			t.Fatalf("%s: failed to load result: %v", tc.name, err)
+		}
+		if len(got) != tc.want {
+			t.Errorf("%s: got %d items, want %d", tc.name, len(got), tc.want)
+		}
+	}
+}
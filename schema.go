@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchemaProperty describes a key of the JSON objects of items.
+type jsonSchemaProperty struct {
+	// A type name, or a list of them
+	Type        any    `json:"type"`
+	Format      string `json:"format,omitempty"`
+	Description string `json:"description"`
+	// States how unknown values are encoded
+	Comment string `json:"$comment,omitempty"`
+}
+
+type jsonSchema struct {
+	Schema               string                        `json:"$schema,omitempty"`
+	Title                string                        `json:"title"`
+	Type                 string                        `json:"type"`
+	Properties           map[string]jsonSchemaProperty `json:"properties"`
+	Required             []string                      `json:"required"`
+	AdditionalProperties bool                          `json:"additionalProperties"`
+}
+
+// itemJSONSchema returns the JSON Schema of the items printed as JSON,
+// restricted to the columns unless nil. The keys and types are derived
+// from the JSON encoding of an item, so the schema follows its struct
+// tags, e.g. keys omitted if empty aren't required.
+func itemJSONSchema(columns []column) (*jsonSchema, error) {
+	var zero item
+	b, err := json.Marshal(&zero)
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON-encode item: %w", err)
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, fmt.Errorf("failed to JSON-decode item: %w", err)
+	}
+
+	all := itemColumns()
+	byName := make(map[string]struct{}, len(all))
+	for _, c := range all {
+		byName[c.name] = struct{}{}
+	}
+	// A key without a column would be missing from the schema
+	for k := range keys {
+		if _, ok := byName[k]; !ok {
+			return nil, fmt.Errorf("no column of item JSON key %q", k)
+		}
+	}
+
+	s := jsonSchema{
+		Schema:     jsonSchemaDialect,
+		Title:      "lmk item",
+		Type:       "object",
+		Properties: make(map[string]jsonSchemaProperty, len(all)),
+		Required:   []string{},
+	}
+	selected := columns
+	if selected == nil {
+		selected = all
+	}
+	for _, c := range selected {
+		p, err := jsonSchemaPropertyOf(c, &zero, columns != nil)
+		if err != nil {
+			return nil, err
+		}
+		s.Properties[c.name] = p
+
+		// Projections contain all of their columns
+		if _, ok := keys[c.name]; ok || columns != nil {
+			s.Required = append(s.Required, c.name)
+		}
+	}
+
+	return &s, nil
+}
+
+// jsonSchemaPropertyOf returns the property of the column. Unknown
+// times are omitted from items, see item.MarshalJSON, projections, if
+// projected is set, contain them as null, see itemProjection.
+func jsonSchemaPropertyOf(c column, itm *item, projected bool) (jsonSchemaProperty, error) {
+	p := jsonSchemaProperty{
+		Description: c.label,
+	}
+	switch c.value(itm).(type) {
+	case string:
+		p.Type = "string"
+	case *time.Time:
+		p.Type, p.Format = "string", "date-time"
+		p.Comment = "Omitted if unknown"
+		if projected {
+			p.Type = []string{"string", "null"}
+			p.Comment = "null if unknown"
+		}
+	case float64:
+		p.Type = "number"
+	case bool:
+		p.Type = "boolean"
+	default:
+		return p, fmt.Errorf("no JSON Schema type of column %q", c.name)
+	}
+	return p, nil
+}
+
+func renderJSONSchema(w io.Writer, columns []column) error {
+	s, err := itemJSONSchema(columns)
+	if err != nil {
+		return err
+	}
+
+	if err := newJSONEncoder(w, true).Encode(s); err != nil {
+		return fmt.Errorf("failed to JSON-print schema: %w", err)
+	}
+
+	return nil
+}
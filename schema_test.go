@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// populatedItem returns an item with all of its JSON-encoded fields set.
+func populatedItem() *item {
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	return &item{
+		Authority:           "Landratsamt Esslingen",
+		PublishedAt:         at,
+		FoundAt:             at.AddDate(0, 0, -7),
+		PublishedAtEnd:      at.AddDate(0, 0, 1),
+		FoundAtEnd:          at.AddDate(0, 0, -6),
+		Name:                "Bäckerei Müller",
+		Address:             "Hauptstraße 1, 73728 Esslingen am Neckar",
+		Reason:              "Mäusekot in der Backstube",
+		LegalBasis:          "§ 11 LFGB",
+		Info:                "Mängel beseitigt",
+		Street:              "Hauptstraße 1",
+		PostalCode:          "73728",
+		City:                "Esslingen am Neckar",
+		State:               "bw",
+		Latitude:            48.74,
+		Longitude:           9.31,
+		MapURL:              "https://www.openstreetmap.org/search?query=Esslingen",
+		FirstSeen:           at.AddDate(0, 0, 2),
+		LastSeen:            at.AddDate(0, 0, 3),
+		Vanished:            true,
+		AuthorityNormalized: "Landratsamt Esslingen",
+		DeletedAt:           at.AddDate(0, 0, 4),
+	}
+}
+
+// schemaTypes returns the type names of p.
+func schemaTypes(t *testing.T, p jsonSchemaProperty) []string {
+	t.Helper()
+
+	switch typ := p.Type.(type) {
+	case string:
+		return []string{typ}
+	case []string:
+		return typ
+	default:
+		t.Fatalf("unexpected type %T of property", p.Type)
+		return nil
+	}
+}
+
+// validateJSONSchema reports where the JSON object b violates s. Only the
+// keywords itemJSONSchema uses are supported.
+func validateJSONSchema(t *testing.T, s *jsonSchema, b []byte) {
+	t.Helper()
+
+	var obj map[string]any
+	if err := json.Unmarshal(b, &obj); err != nil {
+		t.Fatalf("failed to JSON-decode %s: %v", b, err)
+	}
+
+	for _, k := range s.Required {
+		if _, ok := obj[k]; !ok {
+			t.Errorf("%s: required key %q missing", b, k)
+		}
+	}
+	for k, v := range obj {
+		p, ok := s.Properties[k]
+		if !ok {
+			if !s.AdditionalProperties {
+				t.Errorf("%s: key %q not in schema", b, k)
+			}
+			continue
+		}
+
+		var typ string
+		switch v := v.(type) {
+		case nil:
+			typ = "null"
+		case string:
+			typ = "string"
+			if p.Format == "date-time" {
+				if _, err := time.Parse(time.RFC3339, v); err != nil {
+					t.Errorf("%s: key %q is no date-time: %v", b, k, err)
+				}
+			}
+		case float64:
+			typ = "number"
+		case bool:
+			typ = "boolean"
+		default:
+			t.Errorf("%s: unexpected value %v of key %q", b, v, k)
+			continue
+		}
+		if types := schemaTypes(t, p); !slices.Contains(types, typ) {
+			t.Errorf("%s: key %q is a %s, want one of %q", b, k, typ, types)
+		}
+	}
+}
+
+func TestItemJSONSchemaMatchesEncoding(t *testing.T) {
+	t.Parallel()
+
+	all := itemColumns()
+	for _, tc := range []struct {
+		name string
+		itm  *item
+	}{
+		{"populated", populatedItem()},
+		{"zero", &item{}},
+	} {
+		s, err := itemJSONSchema(nil)
+		if err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+		b, err := json.Marshal(tc.itm)
+		if err != nil {
+			t.Fatalf("failed to JSON-encode item: %v", err)
+		}
+		validateJSONSchema(t, s, b)
+
+		// The projection of all columns has the same keys as the item
+		// along with those of unknown times
+		s, err = itemJSONSchema(all)
+		if err != nil {
+			t.Fatalf("failed to create schema of columns: %v", err)
+		}
+		b, err = json.Marshal(itemProjection{itm: tc.itm, columns: all})
+		if err != nil {
+			t.Fatalf("failed to JSON-encode projection: %v", err)
+		}
+		validateJSONSchema(t, s, b)
+	}
+
+	// Unknown times are omitted from items but null in projections, the
+	// schemas must say so
+	plain, err := itemJSONSchema(nil)
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	projected, err := itemJSONSchema(all)
+	if err != nil {
+		t.Fatalf("failed to create schema of columns: %v", err)
+	}
+	for _, c := range all {
+		if _, ok := c.value(&item{}).(*time.Time); !ok {
+			continue
+		}
+		if slices.Contains(plain.Required, c.name) {
+			t.Errorf("time %q is required by the schema of items, but omitted if unknown", c.name)
+		}
+		if types := schemaTypes(t, plain.Properties[c.name]); slices.Contains(types, "null") {
+			t.Errorf("time %q is nullable in the schema of items, but omitted if unknown", c.name)
+		}
+		if types := schemaTypes(t, projected.Properties[c.name]); !slices.Contains(types, "null") {
+			t.Errorf("time %q isn't nullable in the schema of projections, but null if unknown", c.name)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	serveReadHeaderTimeout = 10 * time.Second
+	// Bounds waiting for running requests once stopped
+	serveShutdownTimeout = 10 * time.Second
+)
+
+// runServe serves the stored items over HTTP at addr until ctx is done.
+// Running requests are given serveShutdownTimeout to complete.
+func runServe(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	addr string,
+) error {
+	st, err := openExistingStorage(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	mux, err := newServeMux(l, st)
+	if err != nil {
+		return err
+	}
+
+	ln, err := listen(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	return serveHTTP(ctx, l, ln, mux)
+}
+
+// listen listens on the TCP address. Listening before serving reports
+// errors such as the address being in use right away.
+func listen(ctx context.Context, addr string) (net.Listener, error) {
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	return ln, nil
+}
+
+// serveHTTP serves h on ln until ctx is done, then shuts down gracefully.
+func serveHTTP(ctx context.Context, l *slog.Logger, ln net.Listener, h http.Handler) error {
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: serveReadHeaderTimeout,
+		ErrorLog:          slog.NewLogLogger(l.Handler(), slog.LevelError),
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+	l.InfoContext(ctx, "serving",
+		"addr", ln.Addr().String(),
+	)
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	l.InfoContext(ctx, "shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), serveShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	l.InfoContext(ctx, "stopped serving",
+		"addr", ln.Addr().String(),
+	)
+
+	return nil
+}
+
+func newServeMux(l *slog.Logger, st storage) (*http.ServeMux, error) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /items", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		opts, err := filterOptionsFromQuery(r.URL.Query())
+		if err != nil {
+			writeJSONError(ctx, l, w, http.StatusBadRequest, err)
+			return
+		}
+		f, err := newFilter(opts, time.Now())
+		if err != nil {
+			writeJSONError(ctx, l, w, http.StatusBadRequest, err)
+			return
+		}
+
+		items, err := st.queryItems(ctx, l, f)
+		if err != nil {
+			l.ErrorContext(ctx, err.Error())
+			writeJSONError(ctx, l, w, http.StatusInternalServerError, errors.New("failed to query items"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := renderJSONArray(w, items, nil, false); err != nil {
+			l.ErrorContext(ctx, err.Error())
+		}
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if err := st.ping(ctx); err != nil {
+			l.ErrorContext(ctx, err.Error())
+			writeJSONError(ctx, l, w, http.StatusServiceUnavailable, errors.New("database unavailable"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if _, err := w.Write([]byte("ok\n")); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to write response: %w", err).Error())
+		}
+	})
+
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := renderOpenAPISpec(w, false); err != nil {
+			l.ErrorContext(r.Context(), err.Error())
+		}
+	})
+
+	gql, err := newGraphQLHandler(l, st)
+	if err != nil {
+		return nil, err
+	}
+	mux.Handle("POST /graphql", gql)
+
+	return mux, nil
+}
+
+// itemsQueryParam is a query parameter of /items, read into the field of
+// filterOptions set.
+type itemsQueryParam struct {
+	name        string
+	description string
+
+	str  *string
+	strs *[]string // Of the repeated parameter
+	num  *int
+	flag *bool
+}
+
+// itemsQueryParams returns the query parameters of /items reading into
+// opts, see filterOptionsFromQuery. They are named like the corresponding
+// flags, e.g. published-after or reason, which may be repeated.
+func itemsQueryParams(opts *filterOptions) []itemsQueryParam {
+	return []itemsQueryParam{
+		{name: "published-after", description: "Only items published on or after the date, DD.MM.YYYY", str: &opts.publishedAfter},
+		{name: "published-before", description: "Only items published on or before the date, DD.MM.YYYY", str: &opts.publishedBefore},
+		{name: "since-days", description: "Only items published within the last n days", num: &opts.sinceDays},
+		{name: "found-after", description: "Only items inspected on or after the date, DD.MM.YYYY", str: &opts.foundAfter},
+		{name: "found-before", description: "Only items inspected on or before the date, DD.MM.YYYY", str: &opts.foundBefore},
+		{name: "authority", description: "Only items whose authority contains the substring, case-insensitive", str: &opts.authority},
+		{name: "reason", description: "Only items whose reason contains any of the keywords, case-insensitive", strs: &opts.reasonKeywords},
+		{name: "legal-basis", description: "Only items whose legal basis contains the substring, case-insensitive", str: &opts.legalBasis},
+		{name: "city", description: "Only items whose address contains the substring, case-insensitive", str: &opts.city},
+		{name: "plz", description: "Only items whose postal code starts with the prefix", str: &opts.postalCodePrefix},
+		{name: "state", description: "Only items of any of the Bundesländer, e.g. bw", strs: &opts.states},
+		{name: "name-regex", description: "Only items whose business name matches the regular expression", str: &opts.nameRegex},
+		{name: "dedup", description: "Collapse items with the same business name, address and reason", flag: &opts.dedup},
+		{name: "include-deleted", description: "Include soft-deleted items", flag: &opts.includeDeleted},
+		{name: "sort", description: "Sort items by the field, one of authority, name, published (the default), found", str: &opts.sortField},
+		{name: "desc", description: "Sort in descending order", flag: &opts.sortDesc},
+		{name: "offset", description: "Skip the first n items", num: &opts.offset},
+		{name: "limit", description: "Return at most n items, no limit if 0 or negative", num: &opts.limit},
+	}
+}
+
+// filterOptionsFromQuery reads the filter options from the query
+// parameters of a request, see itemsQueryParams.
+func filterOptionsFromQuery(q url.Values) (filterOptions, error) {
+	opts := filterOptions{
+		sortField: sortByPublished,
+	}
+
+	for _, p := range itemsQueryParams(&opts) {
+		if p.strs != nil {
+			*p.strs = q[p.name]
+			continue
+		}
+
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		switch {
+		case p.str != nil:
+			*p.str = v
+		case p.num != nil:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return opts, fmt.Errorf("invalid value %q of parameter %q", v, p.name)
+			}
+			*p.num = n
+		case p.flag != nil:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return opts, fmt.Errorf("invalid value %q of parameter %q", v, p.name)
+			}
+			*p.flag = b
+		}
+	}
+
+	return opts, nil
+}
+
+// writeJSONError responds with the status and a JSON object describing
+// err.
+func writeJSONError(ctx context.Context, l *slog.Logger, w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{
+		Error: err.Error(),
+	}); err != nil {
+		l.ErrorContext(ctx, fmt.Errorf("failed to write response: %w", err).Error())
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	snapshotDirPermissions  = 0o755
+	snapshotFilePermissions = 0o644
+
+	snapshotFilePrefix = "lmk-"
+	snapshotFileSuffix = ".html"
+	// Sorts chronologically
+	snapshotTimeFormat = "20060102T150405.000000000Z"
+
+	// Older snapshots are deleted
+	snapshotsToKeep = 30
+)
+
+// saveSnapshot writes body to a timestamped file in dir and deletes
+// all but the latest snapshotsToKeep snapshots.
+func saveSnapshot(dir string, now time.Time, body []byte) (string, error) {
+	if err := os.MkdirAll(dir, snapshotDirPermissions); err != nil {
+		return "", fmt.Errorf("failed to create snapshot dir: %w", err)
+	}
+
+	path := filepath.Join(dir, snapshotFilePrefix+now.UTC().Format(snapshotTimeFormat)+snapshotFileSuffix)
+	if err := writeFileAtomic(path, snapshotFilePermissions, func(w io.Writer) error {
+		if _, err := w.Write(body); err != nil {
+			return fmt.Errorf("failed to write: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := pruneSnapshots(dir, snapshotsToKeep); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func pruneSnapshots(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	// Sorted by file name, i.e. oldest first
+	var snapshots []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type().IsRegular() &&
+			strings.HasPrefix(name, snapshotFilePrefix) &&
+			strings.HasSuffix(name, snapshotFileSuffix) {
+			snapshots = append(snapshots, name)
+		}
+	}
+
+	for len(snapshots) > keep {
+		if err := os.Remove(filepath.Join(dir, snapshots[0])); err != nil {
+			return fmt.Errorf("failed to delete old snapshot: %w", err)
+		}
+		snapshots = snapshots[1:]
+	}
+
+	return nil
+}
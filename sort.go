@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+const (
+	sortByAuthority = "authority"
+	sortByName      = "name"
+	sortByPublished = "published"
+	sortByFound     = "found"
+)
+
+// itemOrder orders items by a field. Equal items retain their order,
+// regardless of the direction.
+type itemOrder struct {
+	field string
+	desc  bool
+}
+
+func parseOrder(field string, desc bool) (itemOrder, error) {
+	field = strings.ToLower(field)
+	switch field {
+	case sortByAuthority, sortByName, sortByPublished, sortByFound:
+		return itemOrder{
+			field: field,
+			desc:  desc,
+		}, nil
+	}
+
+	return itemOrder{}, fmt.Errorf(
+		"unknown sort field %q, valid fields are: %s",
+		field,
+		strings.Join([]string{sortByAuthority, sortByName, sortByPublished, sortByFound}, ", "),
+	)
+}
+
+// collator compares strings case-insensitively in German alphabetical
+// order. It is safe for concurrent use.
+type collator struct {
+	mu sync.Mutex
+	c  *collate.Collator
+}
+
+func newCollator() *collator {
+	return &collator{
+		c: collate.New(language.German, collate.IgnoreCase),
+	}
+}
+
+func (c *collator) compare(a, b string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.c.CompareString(a, b)
+}
+
+// compare returns the function comparing items in order o. It returns
+// nil if o is the zero order.
+func (o itemOrder) compare() func(a, b *item) int {
+	var cmp func(a, b *item) int
+	switch o.field {
+	case sortByAuthority:
+		c := newCollator()
+		cmp = func(a, b *item) int { return c.compare(a.Authority, b.Authority) }
+	case sortByName:
+		c := newCollator()
+		cmp = func(a, b *item) int { return c.compare(a.Name, b.Name) }
+	case sortByPublished:
+		cmp = func(a, b *item) int { return a.PublishedAt.Compare(b.PublishedAt) }
+	case sortByFound:
+		cmp = func(a, b *item) int { return a.FoundAt.Compare(b.FoundAt) }
+	default:
+		return nil
+	}
+
+	if o.desc {
+		return func(a, b *item) int { return -cmp(a, b) }
+	}
+	return cmp
+}
+
+func (o itemOrder) sort(items []*item) {
+	if cmp := o.compare(); cmp != nil {
+		slices.SortStableFunc(items, cmp)
+	}
+}
+
+// sql returns the order by expression of o for the items table. Rows
+// are ordered by insertion as a tie-breaker, just like the stable sort.
+func (o itemOrder) sql() string {
+	var expr string
+	switch o.field {
+	case sortByAuthority:
+		expr = "authority collate " + sqliteCollationDE
+	case sortByName:
+		expr = "name collate " + sqliteCollationDE
+	case sortByPublished:
+		expr = "published_at"
+	case sortByFound:
+		expr = "found_at"
+	default:
+		return "id"
+	}
+
+	if o.desc {
+		expr += " desc"
+	}
+	return expr + ", id"
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// Scraped if no other source is selected
+	defaultSourceState = "bw"
+
+	// Key of the config file setting additional sources
+	configSourcesKey = "sources"
+
+	// Bounds the pages of a paginated table followed
+	sourceMaxPages = 50
+)
+
+// itemField identifies the item field a table column is scraped into.
+type itemField int
+
+const (
+	fieldAuthority itemField = iota
+	fieldPublishedAt
+	fieldFoundAt
+	fieldName
+	fieldAddress
+	fieldReason
+	fieldLegalBasis
+	fieldInfo
+)
+
+func (f itemField) set(itm *item, v string) {
+	switch f {
+	case fieldAuthority:
+		itm.Authority = v
+	case fieldPublishedAt:
+		itm.PublishedAtStr = v
+	case fieldFoundAt:
+		itm.FoundAtStr = v
+	case fieldName:
+		itm.Name = v
+	case fieldAddress:
+		itm.Address = v
+	case fieldReason:
+		itm.Reason = v
+	case fieldLegalBasis:
+		itm.LegalBasis = v
+	case fieldInfo:
+		itm.Info = v
+	}
+}
+
+type sourceColumn struct {
+	// Expected in the table heading, a different one means the page
+	// design has changed
+	label string
+	field itemField
+}
+
+// source describes a Lebensmittelkontrolle page listing the items in a
+// table with one row per item.
+type source struct {
+	// Short name of the Bundesland, stored with the scraped items
+	state string
+	url   string
+
+	tableSelector string
+	// Relative to the table
+	headSelector string
+	rowSelector  string
+	// Relative to a row
+	cellSelector string
+	// Selects the link to the next page of a paginated table
+	nextSelector string
+
+	// The table's columns in order
+	columns []sourceColumn
+	// Canonicalizes the authorities of the items, may be nil
+	authorities authorityNormalizer
+}
+
+// sources returns all known sources.
+func sources() []source {
+	return []source{
+		{
+			state: "bw",
+			url:   lmkURL,
+
+			tableSelector: `#consumerInfoTable`,
+			headSelector:  `thead th p`,
+			rowSelector:   `tbody tr`,
+			cellSelector:  `td`,
+			nextSelector:  `a[rel~="next"], .pagination .next a, a.next`,
+
+			columns: []sourceColumn{
+				{labelAuthority, fieldAuthority},
+				{labelPublishedAt, fieldPublishedAt},
+				{labelName, fieldName},
+				{labelAddress, fieldAddress},
+				{labelFoundAt, fieldFoundAt},
+				{labelReason, fieldReason},
+				{labelLegalBasis, fieldLegalBasis},
+				{labelInfo, fieldInfo},
+			},
+		},
+	}
+}
+
+// Of the states of configured sources, see configSources
+var sourceStateRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// nextPageURL returns the absolute URL of the page following the one
+// at pageURL if there is one.
+func nextPageURL(src *source, doc *goquery.Document, pageURL string) (string, bool, error) {
+	href, ok := doc.Find(src.nextSelector).First().Attr("href")
+	if href = strings.TrimSpace(href); !ok || href == "" || strings.HasPrefix(href, "#") {
+		return "", false, nil
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse page URL: %w", err)
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse next page URL %q: %w", href, err)
+	}
+	next := base.ResolveReference(ref)
+	next.Fragment = ""
+
+	return next.String(), true, nil
+}
+
+// withLabels returns a copy of src expecting the given column labels,
+// in the order of its columns, in the table heading.
+func (src *source) withLabels(labels []string) (*source, error) {
+	if got, want := len(labels), len(src.columns); got != want {
+		return nil, fmt.Errorf("invalid number of labels %d/%d", got, want)
+	}
+
+	c := *src
+	c.columns = slices.Clone(src.columns)
+	for i, label := range labels {
+		c.columns[i].label = label
+	}
+
+	return &c, nil
+}
+
+// withAuthorities returns a copy of src canonicalizing the authorities
+// of its items using n.
+func (src *source) withAuthorities(n authorityNormalizer) *source {
+	c := *src
+	c.authorities = n
+	return &c
+}
+
+// readLabels reads the column labels from a file listing one label per
+// line, empty lines are skipped.
+func readLabels(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labels file: %w", err)
+	}
+
+	var labels []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if label := trimText(line); label != "" {
+			labels = append(labels, label)
+		}
+	}
+
+	return labels, nil
+}
+
+// configSources returns the sources of the config file setting v, a
+// mapping of states to the URLs of their pages. The pages must be laid
+// out like the one of the default source, a source replaces the known one
+// of its state.
+func configSources(v any) ([]source, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected a mapping of states to page URLs")
+	}
+
+	layout, err := lookupSource(defaultSourceState)
+	if err != nil {
+		return nil, err
+	}
+	srcs := make([]source, 0, len(m))
+	for state, u := range m {
+		if !sourceStateRegexp.MatchString(state) {
+			return nil, fmt.Errorf("invalid state %q, expected a lowercase short name, e.g. by", state)
+		}
+		s, ok := u.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid page URL of state %q", state)
+		}
+		if err := validateHTTPURL("source", s); err != nil {
+			return nil, err
+		}
+
+		src := *layout
+		src.state, src.url = state, s
+		srcs = append(srcs, src)
+	}
+	// Map iteration order is random
+	slices.SortFunc(srcs, func(a, b source) int { return strings.Compare(a.state, b.state) })
+
+	return srcs, nil
+}
+
+// sourceStates returns the states of all known sources.
+func sourceStates() []string {
+	return states(sources())
+}
+
+func states(srcs []source) []string {
+	states := make([]string, 0, len(srcs))
+	for _, src := range srcs {
+		states = append(states, src.state)
+	}
+	return states
+}
+
+// lookupSources returns the sources of the states in the comma-separated
+// list, each once, the configured ones, see configSources, taking
+// precedence over the known ones.
+func lookupSources(list string, configured []source) ([]*source, error) {
+	all := append(slices.Clone(configured), sources()...)
+
+	var srcs []*source
+	for _, state := range splitList(list) {
+		src, err := lookupSourceOf(all, state)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(srcs, func(s *source) bool { return s.state == src.state }) {
+			srcs = append(srcs, src)
+		}
+	}
+	if len(srcs) == 0 {
+		return nil, errors.New("no source given")
+	}
+
+	return srcs, nil
+}
+
+// lookupSource returns the known source of state.
+func lookupSource(state string) (*source, error) {
+	return lookupSourceOf(sources(), state)
+}
+
+func lookupSourceOf(srcs []source, state string) (*source, error) {
+	for _, src := range srcs {
+		if src.state == strings.ToLower(state) {
+			return &src, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown source %q, valid sources are: %s", state, strings.Join(slices.Compact(slices.Sorted(slices.Values(states(srcs)))), ", "))
+}
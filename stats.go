@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Of the legal bases and businesses listed by the stats subcommand, see
+// -top
+const defaultStatsTop = 10
+
+const (
+	// By the canonical authority, see authorityNormalizer
+	statsAuthoritiesStmt = `
+		select authority_normalized, count(*) from items
+		where deleted_at is null
+		group by authority_normalized
+		order by count(*) desc, authority_normalized
+	`
+	statsLegalBasesStmt = `
+		select legal_basis, count(*) from items
+		where legal_basis != '' and deleted_at is null
+		group by legal_basis
+		order by count(*) desc, legal_basis
+		limit $1
+	`
+	// Items without a valid publication date are skipped
+	statsMonthsStmt = `
+		select %s as month, count(*) from items
+		where published_at > $1 and deleted_at is null
+		group by month
+		order by month
+	`
+	statsBusinessesStmt = `
+		select name, address, count(*) from items
+		where deleted_at is null
+		group by name, address
+		order by count(*) desc, name, address
+		limit $1
+	`
+)
+
+// statsRow is a group of items along with their number.
+type statsRow struct {
+	values []string
+	count  int
+}
+
+// itemStats are aggregates over the stored items, see storage.stats.
+type itemStats struct {
+	authorities []statsRow // Values: authority
+	legalBases  []statsRow // Values: legal basis
+	months      []statsRow // Values: month of publication, e.g. "2025-06"
+	businesses  []statsRow // Values: name, address
+}
+
+func (s *sqlStorage) stats(ctx context.Context, l *slog.Logger, top int) (*itemStats, error) {
+	var st itemStats
+	for _, q := range []struct {
+		rows  *[]statsRow
+		query string
+		args  []any
+	}{
+		{&st.authorities, statsAuthoritiesStmt, nil},
+		{&st.legalBases, statsLegalBasesStmt, []any{top}},
+		{&st.months, fmt.Sprintf(statsMonthsStmt, s.publishedMonthExpr), []any{time.Time{}}},
+		{&st.businesses, statsBusinessesStmt, []any{top}},
+	} {
+		rows, err := s.queryStatsRows(ctx, l, q.query, q.args...)
+		if err != nil {
+			return nil, err
+		}
+		*q.rows = rows
+	}
+
+	return &st, nil
+}
+
+// queryStatsRows runs query, which selects any number of text columns
+// followed by a count.
+func (s *sqlStorage) queryStatsRows(
+	ctx context.Context,
+	l *slog.Logger,
+	query string,
+	args ...any,
+) ([]statsRow, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close rows: %w", err).Error())
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats columns: %w", err)
+	}
+
+	var res []statsRow
+	for rows.Next() {
+		r := statsRow{
+			values: make([]string, len(columns)-1),
+		}
+		dest := make([]any, 0, len(columns))
+		for i := range r.values {
+			dest = append(dest, &r.values[i])
+		}
+		dest = append(dest, &r.count)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan stats: %w", err)
+		}
+		res = append(res, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate stats: %w", err)
+	}
+
+	return res, nil
+}
+
+// runStats prints aggregates over the stored items, listing the top
+// legal bases and businesses only.
+func runStats(
+	ctx context.Context,
+	l *slog.Logger,
+	storageCfg storageConfig,
+	top int,
+	out outputOptions,
+) error {
+	if out.format != outputFormatTable && out.format != outputFormatMarkdown {
+		return errors.New("stats can be printed as a table or as Markdown only")
+	}
+
+	st, err := openExistingStorage(ctx, l, storageCfg)
+	if err != nil {
+		return err
+	}
+	defer st.close(ctx, l)
+
+	stats, err := st.stats(ctx, l, top)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(out.file, func(w io.Writer) error {
+		maxWidth := out.tableMaxWidth
+		if out.format == outputFormatMarkdown {
+			// Markdown cells may be long, see renderMarkdown
+			maxWidth = 0
+		}
+		return renderStats(w, stats, out.format == outputFormatMarkdown, maxWidth)
+	})
+}
+
+// renderStats prints a table per aggregate, the cells truncated to
+// maxWidth characters unless it's 0.
+func renderStats(w io.Writer, stats *itemStats, markdown bool, maxWidth int) error {
+	for i, t := range []struct {
+		title  string
+		header table.Row
+		rows   []statsRow
+	}{
+		{"Einträge je " + labelAuthority, table.Row{labelAuthority, labelCount}, stats.authorities},
+		{"Häufigste " + labelLegalBasis, table.Row{labelLegalBasis, labelCount}, stats.legalBases},
+		{"Einträge je Monat", table.Row{labelMonth, labelCount}, stats.months},
+		{"Häufigste Betriebe", table.Row{labelName, labelAddress, labelCount}, stats.businesses},
+	} {
+		tw := table.NewWriter()
+		tw.SetTitle(t.title)
+		tw.AppendHeader(t.header)
+		tw.SetColumnConfigs([]table.ColumnConfig{
+			{Number: len(t.header), Align: text.AlignRight},
+		})
+		for _, r := range t.rows {
+			row := make(table.Row, 0, len(r.values)+1)
+			for _, v := range r.values {
+				if maxWidth > 0 {
+					v = capstring(v, maxWidth)
+				}
+				row = append(row, v)
+			}
+			tw.AppendRow(append(row, strconv.Itoa(r.count)))
+		}
+
+		s := tw.Render()
+		if markdown {
+			s = tw.RenderMarkdown()
+		}
+		if i > 0 {
+			s = "\n" + s
+		}
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return fmt.Errorf("failed to print stats: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,739 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The statements are understood by both sqlite and Postgres.
+const (
+	insertItemStmt = `
+		insert into items (
+			hash,
+			authority,
+			published_at,
+			found_at,
+			name,
+			address,
+			reason,
+			legal_basis,
+			info,
+			state,
+			first_seen,
+			last_seen,
+			published_at_end,
+			found_at_end,
+			latitude,
+			longitude,
+			street,
+			postal_code,
+			city,
+			authority_normalized,
+			deleted_at
+		) values (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21
+		)
+	`
+	// Bumps the last-seen time of items stored before and fills in the
+	// date range ends, which aren't part of the hash and were not
+	// stored by older versions, as well as coordinates unless the item
+	// wasn't geocoded this time, and the canonical authority as the
+	// normalization table may have changed. Returns whether the item is
+	// new, i.e. was first seen just now.
+	upsertItemStmt = insertItemStmt + `
+		on conflict (hash) do update set
+			last_seen = excluded.last_seen,
+			published_at_end = excluded.published_at_end,
+			found_at_end = excluded.found_at_end,
+			latitude = coalesce(excluded.latitude, items.latitude),
+			longitude = coalesce(excluded.longitude, items.longitude),
+			authority_normalized = excluded.authority_normalized
+		returning coalesce(first_seen = last_seen, false);
+	`
+	// Leaves items stored before untouched
+	insertNewItemStmt = insertItemStmt + `
+		on conflict (hash) do nothing;
+	`
+	deleteItemsBeforeStmt = `
+		delete from items where published_at != $1 and published_at < $2;
+	`
+	// Keeps the time of items soft-deleted before
+	softDeleteItemsBeforeStmt = `
+		update items set deleted_at = $3
+		where published_at != $1 and published_at < $2 and deleted_at is null;
+	`
+	vacuumStmt = `
+		vacuum;
+	`
+	countItemsStmt = `
+		select count(*) from items
+	`
+	selectItemsStmt = `
+		select
+			authority,
+			published_at,
+			found_at,
+			name,
+			address,
+			reason,
+			legal_basis,
+			info,
+			state,
+			first_seen,
+			last_seen,
+			published_at_end,
+			found_at_end,
+			latitude,
+			longitude,
+			street,
+			postal_code,
+			city,
+			authority_normalized,
+			deleted_at
+		from items
+	`
+)
+
+// storage persists scraped items.
+type storage interface {
+	// storeItems stores the items and sets their last-seen time to
+	// seenAt, see storageNow. It returns the items which haven't been
+	// stored before.
+	storeItems(ctx context.Context, l *slog.Logger, seenAt time.Time, items []*item) ([]*item, error)
+	// vanishedItems returns the stored items of the state which haven't
+	// been seen since seenAt, i.e. are no longer published. They are
+	// marked as vanished.
+	vanishedItems(ctx context.Context, l *slog.Logger, state string, seenAt time.Time) ([]*item, error)
+	// lastSeenItems returns the stored items of the state which were seen
+	// by the latest scrape storing items.
+	lastSeenItems(ctx context.Context, l *slog.Logger, state string) ([]*item, error)
+	// recordHistory records the fields of the items changed since the
+	// latest other item of the same business, i.e. of the same state,
+	// name and address, was stored. It returns the number of changes.
+	recordHistory(ctx context.Context, l *slog.Logger, changedAt time.Time, items []*item) (int, error)
+	// itemHistory returns the recorded changes of the businesses of the
+	// items, oldest first.
+	itemHistory(ctx context.Context, l *slog.Logger, items []*item) ([]historyEntry, error)
+	// importItems stores the items at once, items stored before are
+	// skipped. It returns the number of inserted and skipped items.
+	importItems(ctx context.Context, l *slog.Logger, items []*item) (int, int, error)
+	// queryItems returns the stored items which match f.
+	queryItems(ctx context.Context, l *slog.Logger, f *filter) ([]*item, error)
+	// searchItems returns the stored items containing all terms in
+	// their name, address, reason or info, ignoring case.
+	searchItems(ctx context.Context, l *slog.Logger, terms []string) ([]*item, error)
+	// countItems returns the number of stored items which match f. The
+	// order, offset and limit of f are ignored.
+	countItems(ctx context.Context, l *slog.Logger, f *filter) (int, error)
+	// eachItem calls fn for each stored item in the order they were
+	// stored.
+	eachItem(ctx context.Context, l *slog.Logger, fn func(itm *item) error) error
+	// pruneItems deletes the items published before the given time, or
+	// sets their deleted_at time if soft is set. Items without a valid
+	// publication date are kept.
+	pruneItems(ctx context.Context, l *slog.Logger, before time.Time, soft bool) error
+	// stats returns aggregates over the stored items which aren't
+	// soft-deleted, the top legal bases and businesses only.
+	stats(ctx context.Context, l *slog.Logger, top int) (*itemStats, error)
+	// ping checks whether the database is still reachable.
+	ping(ctx context.Context) error
+	close(ctx context.Context, l *slog.Logger)
+}
+
+type storageConfig struct {
+	sqlite      sqliteConfig
+	databaseURL string // Use Postgres instead of sqlite if set
+}
+
+// openStorage opens the configured storage, creating and migrating it
+// as needed.
+func openStorage(ctx context.Context, l *slog.Logger, cfg storageConfig) (storage, error) {
+	if cfg.databaseURL != "" {
+		return openPostgres(ctx, l, cfg.databaseURL)
+	}
+	return openSQLite(ctx, l, cfg.sqlite)
+}
+
+// openExistingStorage is like openStorage but fails if the sqlite
+// database doesn't exist yet.
+func openExistingStorage(ctx context.Context, l *slog.Logger, cfg storageConfig) (storage, error) {
+	if cfg.databaseURL != "" {
+		return openPostgres(ctx, l, cfg.databaseURL)
+	}
+	return openExistingSQLite(ctx, l, cfg.sqlite)
+}
+
+// sqlStorage implements storage for both sqlite and Postgres.
+type sqlStorage struct {
+	db   *sql.DB
+	name string // Of the database system, used in messages
+
+	// Whether the functions used by filter.sql are available, filters
+	// are applied in Go otherwise
+	hasFilterFunctions bool
+	// Whether items_fts is available, see sqlite migration 15, search
+	// terms are matched using LIKE otherwise
+	hasFullTextIndex bool
+	// Formats published_at as e.g. "2025-06"
+	publishedMonthExpr string
+	// Formats a time, stored or a parameter, e.g. "julianday(%s)", to be
+	// compared by its instant rather than e.g. as text of its offset
+	timeExpr string
+}
+
+func (s *sqlStorage) close(ctx context.Context, l *slog.Logger) {
+	if err := s.db.Close(); err != nil {
+		l.ErrorContext(ctx, fmt.Errorf("failed to close %s database: %w", s.name, err).Error())
+	}
+}
+
+func (s *sqlStorage) ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping %s database: %w", s.name, err)
+	}
+	return nil
+}
+
+// storageNow returns the current time as precise as it is stored.
+// Postgres stores microseconds only.
+func storageNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func (s *sqlStorage) storeItems(
+	ctx context.Context,
+	l *slog.Logger,
+	seenAt time.Time,
+	items []*item,
+) ([]*item, error) {
+	// At once, committing each item on its own is slow, e.g. for the
+	// hundreds of items of the first run
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin store transaction: %w", err)
+	}
+
+	newItems, numSeen, err := upsertItems(ctx, l, tx, seenAt, items)
+	if err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit store transaction: %w", err)
+	}
+
+	l.InfoContext(
+		ctx,
+		"successfully stored items",
+		"new", len(newItems),
+		"seen", numSeen,
+	)
+
+	return newItems, nil
+}
+
+// upsertItems stores the items and returns the ones which haven't been
+// stored before along with the number of the others. Items failing to
+// store are logged and skipped, each item is stored within a savepoint
+// as Postgres aborts transactions on errors otherwise. Items of the same
+// hash, e.g. listed twice on a page, are stored once, and new once only.
+func upsertItems(
+	ctx context.Context,
+	l *slog.Logger,
+	tx *sql.Tx,
+	seenAt time.Time,
+	items []*item,
+) ([]*item, int, error) {
+	stmt, err := tx.PrepareContext(ctx, upsertItemStmt)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer func() {
+		if err := stmt.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close insert statement: %w", err).Error())
+		}
+	}()
+
+	newItems := make([]*item, 0, len(items))
+	var numSeen int
+	// Whether the items stored so far were new, by their hash. Their
+	// duplicates would seem new as well, their first-seen and last-seen
+	// times equal after storing them.
+	stored := make(map[string]bool, len(items))
+	for _, itm := range items {
+		hash := itemHash(itm)
+
+		if isNew, ok := stored[hash]; ok {
+			itm.LastSeen = seenAt
+			if isNew {
+				itm.FirstSeen = seenAt
+			}
+			numSeen++
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "savepoint store_item"); err != nil {
+			return nil, 0, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		var isNew bool
+		if err := stmt.QueryRowContext(
+			ctx,
+			itemArgs(hash, itm, nullTime(seenAt), nullTime(seenAt))...,
+		).Scan(&isNew); err != nil {
+			l.ErrorContext(
+				ctx,
+				"failed to exec insert statement",
+				"err", err,
+				"item", fmt.Sprintf("%+v", itm),
+			)
+			if _, err := tx.ExecContext(ctx, "rollback to savepoint store_item"); err != nil {
+				return nil, 0, fmt.Errorf("failed to roll back to savepoint: %w", err)
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "release savepoint store_item"); err != nil {
+			return nil, 0, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		itm.LastSeen = seenAt
+		stored[hash] = isNew
+
+		if !isNew {
+			numSeen++
+			continue
+		}
+
+		itm.FirstSeen = seenAt
+		newItems = append(newItems, itm)
+	}
+
+	return newItems, numSeen, nil
+}
+
+// itemArgs returns the arguments of insertItemStmt.
+func itemArgs(hash string, itm *item, firstSeen, lastSeen sql.NullTime) []any {
+	latitude, longitude := nullCoordinates(itm)
+	return []any{
+		hash,
+		itm.Authority,
+		itm.PublishedAt,
+		itm.FoundAt,
+		itm.Name,
+		itm.Address,
+		itm.Reason,
+		itm.LegalBasis,
+		itm.Info,
+		itm.State,
+		firstSeen,
+		lastSeen,
+		nullTime(itm.PublishedAtEnd),
+		nullTime(itm.FoundAtEnd),
+		latitude,
+		longitude,
+		itm.Street,
+		itm.PostalCode,
+		itm.City,
+		itm.AuthorityNormalized,
+		nullTime(itm.DeletedAt),
+	}
+}
+
+func (s *sqlStorage) vanishedItems(
+	ctx context.Context,
+	l *slog.Logger,
+	state string,
+	seenAt time.Time,
+) ([]*item, error) {
+	var items []*item
+	if err := s.selectItems(ctx, l, func(itm *item) error {
+		itm.Vanished = true
+		items = append(items, itm)
+		return nil
+	}, fmt.Sprintf(
+		" where state = $1 and (last_seen is null or %s < %s) and deleted_at is null order by id",
+		fmt.Sprintf(s.timeExpr, "last_seen"),
+		fmt.Sprintf(s.timeExpr, "$2"),
+	), state, seenAt); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (s *sqlStorage) lastSeenItems(ctx context.Context, l *slog.Logger, state string) ([]*item, error) {
+	var items []*item
+	if err := s.selectItems(ctx, l, func(itm *item) error {
+		items = append(items, itm)
+		return nil
+	}, fmt.Sprintf(
+		" where state = $1 and %[1]s = (select max(%[1]s) from items where state = $1) and deleted_at is null order by id",
+		fmt.Sprintf(s.timeExpr, "last_seen"),
+	), state); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (s *sqlStorage) importItems(ctx context.Context, l *slog.Logger, items []*item) (int, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+
+	inserted, err := insertNewItems(ctx, l, tx, items)
+	if err != nil {
+		return 0, 0, errors.Join(err, tx.Rollback())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit import transaction: %w", err)
+	}
+
+	return inserted, len(items) - inserted, nil
+}
+
+// insertNewItems inserts the items which haven't been stored before and
+// returns their number.
+func insertNewItems(ctx context.Context, l *slog.Logger, tx *sql.Tx, items []*item) (int, error) {
+	stmt, err := tx.PrepareContext(ctx, insertNewItemStmt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer func() {
+		if err := stmt.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close insert statement: %w", err).Error())
+		}
+	}()
+
+	var inserted int
+	for _, itm := range items {
+		hash := itemHash(itm)
+
+		res, err := stmt.ExecContext(
+			ctx,
+			itemArgs(hash, itm, nullTime(itm.FirstSeen), nullTime(itm.LastSeen))...,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to exec insert statement: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get number of inserted items: %w", err)
+		}
+		inserted += int(n)
+	}
+
+	return inserted, nil
+}
+
+// nullTime maps the zero time to NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{
+		Time:  t,
+		Valid: !t.IsZero(),
+	}
+}
+
+// nullCoordinates maps the coordinates of an item which hasn't been
+// geocoded to NULL.
+func nullCoordinates(itm *item) (sql.NullFloat64, sql.NullFloat64) {
+	c := itemCoordinates(itm)
+	if c == nil {
+		return sql.NullFloat64{}, sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: c.Lat, Valid: true}, sql.NullFloat64{Float64: c.Lon, Valid: true}
+}
+
+// pruneItems compacts the database after deleting. Soft-deleted items
+// still occupy their space, which only vacuuming after pruning them
+// without soft set frees.
+func (s *sqlStorage) pruneItems(ctx context.Context, l *slog.Logger, before time.Time, soft bool) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin prune transaction: %w", err)
+	}
+
+	stmt, args := deleteItemsBeforeStmt, []any{time.Time{}, before}
+	if soft {
+		stmt, args = softDeleteItemsBeforeStmt, append(args, storageNow())
+	}
+	res, err := tx.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return errors.Join(
+			fmt.Errorf("failed to delete items: %w", err),
+			tx.Rollback(),
+		)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Join(
+			fmt.Errorf("failed to get number of deleted items: %w", err),
+			tx.Rollback(),
+		)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit prune transaction: %w", err)
+	}
+
+	l.InfoContext(
+		ctx,
+		"successfully pruned items",
+		"before", formatDate(before),
+		"deleted", n,
+		"soft", soft,
+	)
+	if soft {
+		return nil
+	}
+
+	// Must not run within a transaction
+	if _, err := s.db.ExecContext(ctx, vacuumStmt); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqlStorage) queryItems(ctx context.Context, l *slog.Logger, f *filter) ([]*item, error) {
+	var items []*item
+	collect := func(itm *item) error {
+		items = append(items, itm)
+		return nil
+	}
+
+	if !s.hasFilterFunctions {
+		if err := s.eachItem(ctx, l, collect); err != nil {
+			return nil, err
+		}
+		return f.apply(items), nil
+	}
+	if f.dedup {
+		// Needs all matches, sort and bound them afterwards
+		where, args := f.sqlWhere()
+		if err := s.selectItems(ctx, l, collect, where+" order by id", args...); err != nil {
+			return nil, err
+		}
+		return f.apply(items), nil
+	}
+
+	query, args := f.sql()
+	if err := s.selectItems(ctx, l, collect, query, args...); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (s *sqlStorage) searchItems(ctx context.Context, l *slog.Logger, terms []string) ([]*item, error) {
+	where, args := likeSearchWhere(terms)
+	if s.hasFullTextIndex {
+		where, args = " where id in (select rowid from items_fts where items_fts match $1)", []any{ftsQuery(terms)}
+	}
+
+	var items []*item
+	if err := s.selectItems(ctx, l, func(itm *item) error {
+		items = append(items, itm)
+		return nil
+	}, where+" order by id", args...); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ftsQuery returns the FTS5 query matching the items containing all
+// terms, each as a prefix of a word. A term of several words matches
+// them as a phrase.
+func ftsQuery(terms []string) string {
+	phrases := make([]string, 0, len(terms))
+	for _, t := range terms {
+		phrases = append(phrases, `"`+strings.ReplaceAll(t, `"`, `""`)+`"*`)
+	}
+	return strings.Join(phrases, " AND ")
+}
+
+// Escapes the wildcards of LIKE patterns
+//
+//nolint:gochecknoglobals // It's a constant replacer
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// likeSearchWhere returns the condition matching the items containing
+// all terms, for databases without a full-text index.
+func likeSearchWhere(terms []string) (string, []any) {
+	columns := []string{"name", "address", "reason", "info"}
+
+	conds := make([]string, 0, len(terms))
+	args := make([]any, 0, len(terms))
+	for i, t := range terms {
+		p := "$" + strconv.Itoa(i+1)
+		ors := make([]string, 0, len(columns))
+		for _, c := range columns {
+			ors = append(ors, "lower("+c+") like "+p+` escape '\'`)
+		}
+		conds = append(conds, "("+strings.Join(ors, " or ")+")")
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(t))+"%")
+	}
+	return " where " + strings.Join(conds, " and "), args
+}
+
+func (s *sqlStorage) countItems(ctx context.Context, l *slog.Logger, f *filter) (int, error) {
+	if f.dedup {
+		unbounded := *f
+		unbounded.offset, unbounded.limit = 0, 0
+		items, err := s.queryItems(ctx, l, &unbounded)
+		if err != nil {
+			return 0, err
+		}
+		return len(items), nil
+	}
+	if !s.hasFilterFunctions {
+		var n int
+		if err := s.eachItem(ctx, l, func(itm *item) error {
+			if f.match(itm) {
+				n++
+			}
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+
+	where, args := f.sqlWhere()
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, countItemsStmt+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count items: %w", err)
+	}
+
+	return n, nil
+}
+
+func (s *sqlStorage) eachItem(ctx context.Context, l *slog.Logger, fn func(itm *item) error) error {
+	return s.selectItems(ctx, l, fn, " order by id")
+}
+
+// selectItems calls fn for each item selected by selectItemsStmt
+// followed by query. The rows are streamed rather than loaded at once.
+func (s *sqlStorage) selectItems(
+	ctx context.Context,
+	l *slog.Logger,
+	fn func(itm *item) error,
+	query string,
+	args ...any,
+) error {
+	rows, err := s.db.QueryContext(ctx, selectItemsStmt+query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query items: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			l.ErrorContext(ctx, fmt.Errorf("failed to close rows: %w", err).Error())
+		}
+	}()
+
+	for rows.Next() {
+		itm, err := scanItem(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(itm); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate items: %w", err)
+	}
+
+	return nil
+}
+
+// dbTime scans times stored as text by sqlite as well as native ones.
+// NULL is scanned as the zero time.
+type dbTime struct {
+	time.Time
+}
+
+func (t *dbTime) Scan(v any) error {
+	switch v := v.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	default:
+		return fmt.Errorf("failed to scan database time of type %T", v)
+	}
+
+	return nil
+}
+
+func (t *dbTime) parse(s string) error {
+	parsed, err := time.Parse(sqliteTimeFormat, s)
+	if err != nil {
+		return fmt.Errorf("failed to parse database time %q: %w", s, err)
+	}
+	t.Time = parsed
+	return nil
+}
+
+// scanItem scans a row selected by selectItemsStmt.
+func scanItem(rows *sql.Rows) (*item, error) {
+	var (
+		itm                                       item
+		publishedAt, foundAt, firstSeen, lastSeen dbTime
+		publishedAtEnd, foundAtEnd, deletedAt     dbTime
+		latitude, longitude                       sql.NullFloat64
+	)
+	if err := rows.Scan(
+		&itm.Authority,
+		&publishedAt,
+		&foundAt,
+		&itm.Name,
+		&itm.Address,
+		&itm.Reason,
+		&itm.LegalBasis,
+		&itm.Info,
+		&itm.State,
+		&firstSeen,
+		&lastSeen,
+		&publishedAtEnd,
+		&foundAtEnd,
+		&latitude,
+		&longitude,
+		&itm.Street,
+		&itm.PostalCode,
+		&itm.City,
+		&itm.AuthorityNormalized,
+		&deletedAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to scan item: %w", err)
+	}
+	itm.PublishedAt = publishedAt.Time
+	itm.FoundAt = foundAt.Time
+	itm.FirstSeen = firstSeen.Time
+	itm.LastSeen = lastSeen.Time
+	itm.PublishedAtEnd = publishedAtEnd.Time
+	itm.FoundAtEnd = foundAtEnd.Time
+	itm.DeletedAt = deletedAt.Time
+	itm.Latitude = latitude.Float64
+	itm.Longitude = longitude.Float64
+	itm.MapURL = mapURL(itm.Address)
+
+	// The scraped date strings aren't stored, dates which couldn't be
+	// parsed are lost.
+	itm.PublishedAtStr = formatDate(itm.PublishedAt)
+	itm.FoundAtStr = formatDate(itm.FoundAt)
+
+	return &itm, nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// benchItems returns n distinct items spread over the authorities and
+// about five years of publication dates, like months of daily scrapes.
+func benchItems(n int) []*item {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	items := make([]*item, 0, n)
+	for i := range n {
+		published := start.AddDate(0, 0, i%(5*365)) //nolint:mnd // Five years
+		itm := &item{
+			State:       "bw",
+			Authority:   fmt.Sprintf("Landratsamt %d", i%40), //nolint:mnd // As many as there are in Baden-Württemberg
+			PublishedAt: published,
+			FoundAt:     published.AddDate(0, 0, -7),
+			Name:        fmt.Sprintf("Betrieb %d", i),
+			Address:     fmt.Sprintf("Hauptstraße %d, 70173 Stuttgart", i%200), //nolint:mnd // Some share the street
+			Reason:      "Mängel bei der Betriebshygiene",
+			LegalBasis:  "§ 40 Abs. 1a LFGB",
+		}
+		setAddressParts(itm)
+		items = append(items, itm)
+	}
+
+	return items
+}
+
+// openTestSQLite opens a new sqlite database in the test's temporary
+// directory.
+func openTestSQLite(ctx context.Context, t *testing.T) *sqlStorage {
+	t.Helper()
+
+	l := testLogger()
+	st, err := openSQLite(ctx, l, sqliteConfig{file: filepath.Join(t.TempDir(), "db.sqlite")})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { st.close(ctx, l) })
+
+	return st
+}
+
+func TestStoreItemsDuplicate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+	st := openTestSQLite(ctx, t)
+
+	// The first item is listed twice, e.g. on two pages
+	items := benchItems(2)
+	dup := *items[0]
+	items = append(items, &dup)
+
+	seenAt := storageNow()
+	newItems, err := st.storeItems(ctx, l, seenAt, items)
+	if err != nil {
+		t.Fatalf("failed to store items: %v", err)
+	}
+	if len(newItems) != 2 { //nolint:mnd // The distinct ones
+		t.Errorf("got %d new items, want 2, the duplicate isn't new on its own", len(newItems))
+	}
+	if !dup.FirstSeen.Equal(seenAt) || !dup.LastSeen.Equal(seenAt) {
+		t.Errorf("got duplicate first seen %v, last seen %v, want %v", dup.FirstSeen, dup.LastSeen, seenAt)
+	}
+	var n int
+	if err := st.db.QueryRowContext(ctx, countItemsStmt).Scan(&n); err != nil {
+		t.Fatalf("failed to count items: %v", err)
+	}
+	if n != 2 { //nolint:mnd // See above
+		t.Errorf("got %d stored items, want 2", n)
+	}
+
+	// Neither is new once stored
+	newItems, err = st.storeItems(ctx, l, storageNow(), items)
+	if err != nil {
+		t.Fatalf("failed to store items again: %v", err)
+	}
+	if len(newItems) != 0 {
+		t.Errorf("got %d new items storing them again, want none", len(newItems))
+	}
+}
+
+func TestSeenItemsMixedOffsets(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := testLogger()
+	st := openTestSQLite(ctx, t)
+
+	// The times are stored in the local offset, which changed between
+	// the scrapes, e.g. at the end of daylight saving time. The later
+	// scrape's time sorts first as text.
+	summer := time.FixedZone("CEST", 2*60*60)
+	winter := time.FixedZone("CET", 1*60*60)
+	first := time.Date(2024, 10, 27, 2, 30, 0, 0, summer)
+	second := time.Date(2024, 10, 27, 2, 10, 0, 0, winter)
+	if !second.After(first) {
+		t.Fatalf("second scrape at %v isn't after the first at %v", second, first)
+	}
+
+	items := benchItems(2)
+	gone, kept := items[0], items[1]
+	if _, err := st.storeItems(ctx, l, first, items); err != nil {
+		t.Fatalf("failed to store items: %v", err)
+	}
+	if _, err := st.storeItems(ctx, l, second, []*item{kept}); err != nil {
+		t.Fatalf("failed to store items again: %v", err)
+	}
+
+	vanished, err := st.vanishedItems(ctx, l, "bw", second)
+	if err != nil {
+		t.Fatalf("failed to get vanished items: %v", err)
+	}
+	if got := itemNames(vanished); !slices.Equal(got, []string{gone.Name}) {
+		t.Errorf("got vanished items %q, want %q", got, gone.Name)
+	}
+
+	seen, err := st.lastSeenItems(ctx, l, "bw")
+	if err != nil {
+		t.Fatalf("failed to get last seen items: %v", err)
+	}
+	if got := itemNames(seen); !slices.Equal(got, []string{kept.Name}) {
+		t.Errorf("got last seen items %q, want %q", got, kept.Name)
+	}
+}
+
+// openBenchSQLite opens the in-memory database, emptied, it's shared by
+// all benchmarks.
+func openBenchSQLite(ctx context.Context, b *testing.B) *sqlStorage {
+	b.Helper()
+
+	l := testLogger()
+	st, err := openSQLite(ctx, l, sqliteConfig{file: sqliteMemoryFile})
+	if err != nil {
+		b.Fatalf("failed to open database: %v", err)
+	}
+	b.Cleanup(func() { st.close(ctx, l) })
+
+	if _, err := st.db.ExecContext(ctx, `delete from items;`); err != nil {
+		b.Fatalf("failed to empty database: %v", err)
+	}
+
+	return st
+}
+
+// BenchmarkQueryItems queries a month of an authority's items out of
+// 300,000, with the indexes of sqliteMigrations and without. The indexes
+// make it about 20 times faster, 13ms rather than 280ms a query on a
+// Xeon server. Seeding the database takes most of the run time.
+func BenchmarkQueryItems(b *testing.B) {
+	ctx := context.Background()
+	l := testLogger()
+	st := openBenchSQLite(ctx, b)
+
+	if _, _, err := st.importItems(ctx, l, benchItems(300_000)); err != nil { //nolint:mnd // A few years of scrapes
+		b.Fatalf("failed to seed database: %v", err)
+	}
+
+	f, err := newFilter(filterOptions{
+		authority:       "Landratsamt 7",
+		publishedAfter:  "01.03.2022",
+		publishedBefore: "31.03.2022",
+		sortField:       sortByPublished,
+		sortDesc:        true,
+		limit:           100, //nolint:mnd // A page of results
+	}, time.Now())
+	if err != nil {
+		b.Fatalf("failed to create filter: %v", err)
+	}
+
+	query := func(b *testing.B) {
+		b.Helper()
+
+		for range b.N {
+			items, err := st.queryItems(ctx, l, f)
+			if err != nil {
+				b.Fatalf("failed to query items: %v", err)
+			}
+			if len(items) == 0 {
+				b.Fatal("got no items")
+			}
+		}
+	}
+
+	b.Run("indexed", query)
+	b.Run("unindexed", func(b *testing.B) {
+		if _, err := st.db.ExecContext(ctx, `
+			drop index items_published_at;
+			drop index items_authority;
+			drop index items_found_at;
+		`); err != nil {
+			b.Fatalf("failed to drop indexes: %v", err)
+		}
+		b.Cleanup(func() {
+			if _, err := st.db.ExecContext(ctx, `
+				create index items_published_at on items (published_at);
+				create index items_authority on items (authority);
+				create index items_found_at on items (found_at);
+			`); err != nil {
+				b.Errorf("failed to recreate indexes: %v", err)
+			}
+		})
+
+		query(b)
+	})
+}
+
+// BenchmarkInsert stores the items of a first run, all of them new, in
+// a single transaction, see storeItems.
+func BenchmarkInsert(b *testing.B) {
+	ctx := context.Background()
+	l := testLogger()
+	st := openBenchSQLite(ctx, b)
+
+	items := benchItems(1000) //nolint:mnd // A large first run
+	for range b.N {
+		b.StopTimer()
+		if _, err := st.db.ExecContext(ctx, `delete from items;`); err != nil {
+			b.Fatalf("failed to empty database: %v", err)
+		}
+		b.StartTimer()
+
+		stored, err := st.storeItems(ctx, l, time.Now(), items)
+		if err != nil {
+			b.Fatalf("failed to store items: %v", err)
+		}
+		if len(stored) != len(items) {
+			b.Fatalf("got %d new items, want %d", len(stored), len(items))
+		}
+	}
+}
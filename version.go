@@ -0,0 +1,56 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at build time, e.g. with -ldflags "-X main.version=v1.2.3". The
+// build info embedded by the Go toolchain is used if unset.
+//
+//nolint:gochecknoglobals // Set by the linker
+var (
+	version string
+	commit  string
+)
+
+// buildVersion describes the build, e.g.
+// "lmk v1.2.3 (commit 0123abc, modified) go1.24.0".
+func buildVersion() string {
+	v, c, modified := version, commit, false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" {
+			v = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if c == "" {
+					c = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+
+	var b strings.Builder
+	b.WriteString("lmk ")
+	b.WriteString(v)
+	if c != "" {
+		b.WriteString(" (commit ")
+		b.WriteString(c)
+		if modified {
+			b.WriteString(", modified")
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(" ")
+	b.WriteString(runtime.Version())
+
+	return b.String()
+}